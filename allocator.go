@@ -0,0 +1,53 @@
+package gobuff
+
+// Allocator supplies the backing arrays of Buffers, for example from an arena
+// or off-heap memory. Alloc must return a slice with capacity of at least n;
+// its length is ignored. Free receives arrays the Buffer or pool no longer
+// uses. A Buffer without an Allocator uses make and leaves freeing to the GC.
+//
+// A freed array may be reused immediately, so slices previously returned by
+// Bytes, Peek, Reserve or similar become invalid once the buffer grows.
+type Allocator interface {
+	Alloc(n int) []byte
+	Free(p []byte)
+}
+
+// NewBufferWithAllocator creates a buffer whose backing arrays, including
+// every reallocation on growth, come from a. A nil a behaves like NewBuffer.
+func NewBufferWithAllocator(initialCap int, a Allocator) *Buffer {
+	if a == nil {
+		return NewBuffer(initialCap)
+	}
+	if initialCap < 0 {
+		initialCap = 0
+	}
+	return &Buffer{buf: a.Alloc(initialCap)[:0], alloc: a}
+}
+
+// makeBuf returns an array of length n and capacity c from the buffer's
+// allocator, or from make without one.
+func (b *Buffer) makeBuf(n, c int) []byte {
+	if b.alloc == nil {
+		return make([]byte, n, c)
+	}
+	return b.alloc.Alloc(c)[:n]
+}
+
+// freeBuf hands p back to the buffer's allocator, if any.
+func (b *Buffer) freeBuf(p []byte) {
+	if b.alloc != nil && cap(p) > 0 {
+		b.alloc.Free(p[:0])
+	}
+}
+
+// newBuffer creates a pooled buffer using the pool's Allocator.
+func (p *BufferPool) newBuffer(capacity int) *Buffer {
+	return NewBufferWithAllocator(capacity, p.alloc)
+}
+
+// discard releases a buffer the pool will not keep back to its allocator.
+func (p *BufferPool) discard(b *Buffer) {
+	p.refundBudget(b)
+	b.freeBuf(b.buf)
+	b.buf = nil
+}
@@ -0,0 +1,109 @@
+package gobuff
+
+import "testing"
+
+// countingAllocator tracks outstanding arrays handed out through Alloc.
+type countingAllocator struct {
+	allocs, frees int
+	live          map[*byte]bool
+}
+
+func (a *countingAllocator) Alloc(n int) []byte {
+	a.allocs++
+	p := make([]byte, 0, n+1) // +1 so zero-size arrays have a distinct address
+	if a.live == nil {
+		a.live = make(map[*byte]bool)
+	}
+	a.live[&p[:1][0]] = true
+	return p
+}
+
+func (a *countingAllocator) Free(p []byte) {
+	key := &p[:1][0]
+	if !a.live[key] {
+		panic("free of array not owned by allocator")
+	}
+	delete(a.live, key)
+	a.frees++
+}
+
+func TestBufferAllocatorGrow(t *testing.T) {
+	a := &countingAllocator{}
+	b := NewBufferWithAllocator(4, a)
+	for i := 0; i < 100; i++ {
+		_, _ = b.WriteString("0123456789")
+	}
+	if b.Len() != 1000 {
+		t.Fatalf("len = %d", b.Len())
+	}
+	if a.allocs < 2 || a.frees != a.allocs-1 {
+		t.Fatalf("allocs=%d frees=%d, want every replaced array freed", a.allocs, a.frees)
+	}
+	b.ResetAndFree()
+	if len(a.live) != 0 {
+		t.Fatalf("%d arrays still live after ResetAndFree", len(a.live))
+	}
+}
+
+func TestBufferAllocatorNil(t *testing.T) {
+	b := NewBufferWithAllocator(8, nil)
+	if b.alloc != nil || b.Cap() != 8 {
+		t.Fatalf("nil allocator should behave like NewBuffer")
+	}
+}
+
+func TestBufferPoolAllocator(t *testing.T) {
+	a := &countingAllocator{}
+	pool := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:      []int{64, 256},
+		BoundedFreelists: true,
+		MaxCap:           256,
+		Allocator:        a,
+	})
+	buf := pool.GetSized(100)
+	if a.allocs != 1 {
+		t.Fatalf("allocs = %d, want pool to allocate through Allocator", a.allocs)
+	}
+	_, _ = buf.Write(make([]byte, 1000)) // grows past MaxCap
+	pool.Put(buf)
+	if len(a.live) != 0 {
+		t.Fatalf("%d arrays live after oversize drop, want 0", len(a.live))
+	}
+	if got := pool.Stats().OversizeDrops; got != 1 {
+		t.Fatalf("OversizeDrops = %d, want 1", got)
+	}
+}
+
+func TestBufferConcatFromAllocators(t *testing.T) {
+	a := &countingAllocator{}
+	dst := NewBufferWithAllocator(8, a)
+	src := NewBuffer(8)
+	_, _ = src.WriteString("payload")
+	if n, err := dst.ConcatFrom(src); err != nil || n != 7 || dst.String() != "payload" {
+		t.Fatalf("ConcatFrom = %d, %v, %q", n, err, dst.String())
+	}
+	if src.Len() != 0 {
+		t.Fatalf("src not drained")
+	}
+	dst.ResetAndFree() // panics if dst holds src's heap array
+	if len(a.live) != 0 {
+		t.Fatalf("%d arrays leaked", len(a.live))
+	}
+}
+
+func TestByteBufferPoolAllocator(t *testing.T) {
+	a := &countingAllocator{}
+	pool := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:      []int{64},
+		BoundedFreelists: true,
+		MaxCap:           64,
+		Allocator:        a,
+	})
+	bp := NewByteBufferPool(pool)
+	bb := bp.Get()
+	bb.B = append(bb.B, make([]byte, 1000)...) // outgrows the allocator's array
+	bp.Put(bb)                                 // dropped past MaxCap; Free panics on a heap slice
+	if len(a.live) != 0 {
+		t.Fatalf("%d arrays leaked", len(a.live))
+	}
+}
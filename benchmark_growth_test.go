@@ -0,0 +1,62 @@
+package gobuff
+
+import (
+	"bytes"
+	"testing"
+)
+
+var growthStrategies = []struct {
+	name     string
+	strategy GrowthStrategy
+}{
+	{"PowerOfTwo", GrowPowerOfTwo},
+	{"Exact", GrowExact},
+	{"Factor1_5", GrowFactor1_5},
+	{"Dampened", GrowDampened},
+}
+
+// appendWorkload writes a mix of small and medium chunks, as an encoder building
+// a message would, into a fresh buffer using the given strategy.
+func appendWorkload(s GrowthStrategy, chunks [][]byte, rounds int) *Buffer {
+	buf := NewBuffer(0)
+	buf.SetGrowthStrategy(s)
+	for i := 0; i < rounds; i++ {
+		_, _ = buf.Write(chunks[i%len(chunks)])
+	}
+	return buf
+}
+
+func growthChunks() [][]byte {
+	return [][]byte{
+		bytes.Repeat([]byte("a"), 7),
+		bytes.Repeat([]byte("b"), 60),
+		bytes.Repeat([]byte("c"), 300),
+		bytes.Repeat([]byte("d"), 1200),
+	}
+}
+
+func TestGrowthStrategiesContents(t *testing.T) {
+	chunks := growthChunks()
+	want := appendWorkload(GrowPowerOfTwo, chunks, 64).Bytes()
+	for _, gs := range growthStrategies {
+		got := appendWorkload(gs.strategy, chunks, 64)
+		if !bytes.Equal(got.Bytes(), want) {
+			t.Fatalf("%s: contents mismatch", gs.name)
+		}
+		if got.Cap() < got.Len() {
+			t.Fatalf("%s: cap %d below len %d", gs.name, got.Cap(), got.Len())
+		}
+	}
+}
+
+func BenchmarkBufferGrowthStrategy(b *testing.B) {
+	chunks := growthChunks()
+	for _, gs := range growthStrategies {
+		b.Run(gs.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				sinkInt = appendWorkload(gs.strategy, chunks, 64).Len()
+			}
+		})
+	}
+}
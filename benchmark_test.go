@@ -3,6 +3,7 @@ package gobuff
 import (
 	"bytes"
 	"io"
+	"strconv"
 	"sync"
 	"testing"
 )
@@ -47,6 +48,72 @@ func BenchmarkBufferWrite(b *testing.B) {
 	}
 }
 
+func BenchmarkAppendBufferWrite(b *testing.B) {
+	payload := []byte("hello world")
+	b.ReportAllocs()
+	b.ResetTimer()
+	buf := NewAppendBuffer(64)
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		_, _ = buf.Write(payload)
+		_, _ = buf.Write(payload)
+		sinkInt = buf.Len()
+	}
+}
+
+var templateParts = []string{"<li class=\"", "item", "\" id=\"", "42", "\">", "Hello, ", "world", "</li>\n"}
+
+func BenchmarkBufferWriteStrings(b *testing.B) {
+	b.Run("WriteStrings", func(b *testing.B) {
+		buf := NewBuffer(0)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			for j := 0; j < 16; j++ {
+				_, _ = buf.WriteStrings(templateParts...)
+			}
+			sinkInt = buf.Len()
+		}
+	})
+	b.Run("RepeatedWriteString", func(b *testing.B) {
+		buf := NewBuffer(0)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			for j := 0; j < 16; j++ {
+				for _, s := range templateParts {
+					_, _ = buf.WriteString(s)
+				}
+			}
+			sinkInt = buf.Len()
+		}
+	})
+}
+
+func BenchmarkBufferWriteRepeat(b *testing.B) {
+	const count = 256
+	b.Run("WriteRepeat", func(b *testing.B) {
+		buf := NewBuffer(0)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			_, _ = buf.WriteRepeat(' ', count)
+			sinkInt = buf.Len()
+		}
+	})
+	b.Run("WriteByteLoop", func(b *testing.B) {
+		buf := NewBuffer(0)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			for j := 0; j < count; j++ {
+				_ = buf.WriteByte(' ')
+			}
+			sinkInt = buf.Len()
+		}
+	})
+}
+
 func BenchmarkBufferPoolWriteBytes(b *testing.B) {
 	pool := NewBufferPoolWithOptions(PoolOptions{
 		InitialCap:         64,
@@ -160,3 +227,143 @@ func BenchmarkBytesBufferNoPool(b *testing.B) {
 		sinkInt = n
 	}
 }
+
+// countingReader serves data in chunks no larger than the caller's slice and
+// records how many Read calls were made.
+type countingReader struct {
+	data  []byte
+	off   int
+	calls int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.calls++
+	if c.off >= len(c.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, c.data[c.off:])
+	c.off += n
+	return n, nil
+}
+
+// bufferedReader is a countingReader that reports its remaining data through
+// Buffered, the size hint ReadFromMin checks for.
+type bufferedReader struct {
+	*countingReader
+}
+
+func (r bufferedReader) Buffered() int {
+	return len(r.data) - r.off
+}
+
+func BenchmarkBufferReadFromMin(b *testing.B) {
+	payload := bytes.Repeat([]byte("a"), 256*1024)
+	for _, hinted := range []bool{false, true} {
+		for _, minRead := range []int{512, 4096, 16384} {
+			name := strconv.Itoa(minRead)
+			if hinted {
+				name = "Buffered/" + name
+			}
+			b.Run(name, func(b *testing.B) {
+				cr := &countingReader{data: payload}
+				var src io.Reader = cr
+				if hinted {
+					src = bufferedReader{cr}
+				}
+				var calls int
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					buf := NewBuffer(0)
+					cr.off, cr.calls = 0, 0
+					_, _ = buf.ReadFromMin(src, minRead)
+					calls += cr.calls
+				}
+				b.ReportMetric(float64(calls)/float64(b.N), "reads/op")
+			})
+		}
+	}
+}
+
+func BenchmarkIOCopyFromBuffer(b *testing.B) {
+	payload := bytes.Repeat([]byte("a"), 4096)
+	buf := NewBuffer(len(payload))
+	dst := &struct{ io.Writer }{io.Discard}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = buf.Write(payload)
+		n, _ := io.Copy(dst, buf)
+		sinkInt = int(n)
+	}
+}
+
+func BenchmarkIOCopyIntoBuffer(b *testing.B) {
+	payload := bytes.Repeat([]byte("a"), 4096)
+	src := bytes.NewReader(payload)
+	wrapped := &struct{ io.Reader }{src}
+	buf := NewBuffer(2 * len(payload))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		src.Reset(payload)
+		n, _ := io.Copy(buf, wrapped)
+		sinkInt = int(n)
+	}
+}
+
+func BenchmarkBufferLargeWrite(b *testing.B) {
+	payload := bytes.Repeat([]byte("a"), 100*1024+1)
+	b.Run("FastPath", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf := NewBuffer(16)
+			_, _ = buf.Write(payload)
+			sinkInt = buf.Len()
+		}
+	})
+	b.Run("GrowThenAppend", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf := NewBuffer(16)
+			buf.Grow(len(payload))
+			_, _ = buf.Write(payload)
+			sinkInt = buf.Len()
+		}
+	})
+}
+
+func BenchmarkBufferPoolSharded(b *testing.B) {
+	for _, sharded := range []bool{false, true} {
+		for _, workers := range []int{32, 64} {
+			name := "Unsharded"
+			if sharded {
+				name = "Sharded"
+			}
+			b.Run(name+"/"+strconv.Itoa(workers), func(b *testing.B) {
+				pool := NewBufferPoolWithOptions(PoolOptions{
+					BucketSizes: []int{64, 256, 1024, 4096},
+					Sharded:     sharded,
+				})
+				payload := []byte("hello world")
+				per := b.N/workers + 1
+				b.ReportAllocs()
+				b.ResetTimer()
+				var wg sync.WaitGroup
+				wg.Add(workers)
+				for w := 0; w < workers; w++ {
+					go func() {
+						defer wg.Done()
+						for i := 0; i < per; i++ {
+							buf := pool.GetSized(1000)
+							_, _ = buf.Write(payload)
+							pool.Put(buf)
+						}
+					}()
+				}
+				wg.Wait()
+			})
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package gobuff
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrVarintOverflow is returned when a varint does not fit in 64 bits.
+var ErrVarintOverflow = errors.New("gobuff: varint overflows a 64-bit integer")
+
+// WriteUint16 appends v encoded in the given byte order. It returns
+// ErrBufferFull, writing nothing, if that would exceed the SetMaxSize limit.
+func (b *Buffer) WriteUint16(order binary.ByteOrder, v uint16) error {
+	if b.exceeds(2) {
+		return ErrBufferFull
+	}
+	order.PutUint16(b.Reserve(2), v)
+	return nil
+}
+
+// WriteUint32 appends v encoded in the given byte order. It returns
+// ErrBufferFull, writing nothing, if that would exceed the SetMaxSize limit.
+func (b *Buffer) WriteUint32(order binary.ByteOrder, v uint32) error {
+	if b.exceeds(4) {
+		return ErrBufferFull
+	}
+	order.PutUint32(b.Reserve(4), v)
+	return nil
+}
+
+// WriteUint64 appends v encoded in the given byte order. It returns
+// ErrBufferFull, writing nothing, if that would exceed the SetMaxSize limit.
+func (b *Buffer) WriteUint64(order binary.ByteOrder, v uint64) error {
+	if b.exceeds(8) {
+		return ErrBufferFull
+	}
+	order.PutUint64(b.Reserve(8), v)
+	return nil
+}
+
+// ReadUint16 consumes a 2-byte integer in the given byte order. If fewer than
+// 2 bytes remain it consumes nothing and returns io.ErrUnexpectedEOF.
+func (b *Buffer) ReadUint16(order binary.ByteOrder) (uint16, error) {
+	p, err := b.nextFixed(2)
+	if err != nil {
+		return 0, err
+	}
+	return order.Uint16(p), nil
+}
+
+// ReadUint32 consumes a 4-byte integer in the given byte order. If fewer than
+// 4 bytes remain it consumes nothing and returns io.ErrUnexpectedEOF.
+func (b *Buffer) ReadUint32(order binary.ByteOrder) (uint32, error) {
+	p, err := b.nextFixed(4)
+	if err != nil {
+		return 0, err
+	}
+	return order.Uint32(p), nil
+}
+
+// ReadUint64 consumes an 8-byte integer in the given byte order. If fewer than
+// 8 bytes remain it consumes nothing and returns io.ErrUnexpectedEOF.
+func (b *Buffer) ReadUint64(order binary.ByteOrder) (uint64, error) {
+	p, err := b.nextFixed(8)
+	if err != nil {
+		return 0, err
+	}
+	return order.Uint64(p), nil
+}
+
+// nextFixed consumes exactly n bytes or none at all.
+func (b *Buffer) nextFixed(n int) ([]byte, error) {
+	if b.Len() < n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return b.Next(n), nil
+}
+
+// WriteUvarint appends v as an unsigned varint directly into the backing array
+// and returns the number of bytes written. It returns ErrBufferFull, writing
+// nothing, if that would exceed the SetMaxSize limit.
+func (b *Buffer) WriteUvarint(v uint64) (int, error) {
+	n := uvarintLen(v)
+	if b.exceeds(n) {
+		return 0, ErrBufferFull
+	}
+	b.grow(n)
+	prev := len(b.buf)
+	b.buf = binary.AppendUvarint(b.buf, v)
+	return len(b.buf) - prev, nil
+}
+
+// WriteVarint appends v as a zig-zag encoded signed varint and returns the
+// number of bytes written. It returns ErrBufferFull, writing nothing, if that
+// would exceed the SetMaxSize limit.
+func (b *Buffer) WriteVarint(v int64) (int, error) {
+	ux := uint64(v) << 1
+	if v < 0 {
+		ux = ^ux
+	}
+	n := uvarintLen(ux)
+	if b.exceeds(n) {
+		return 0, ErrBufferFull
+	}
+	b.grow(n)
+	prev := len(b.buf)
+	b.buf = binary.AppendVarint(b.buf, v)
+	return len(b.buf) - prev, nil
+}
+
+// uvarintLen returns the encoded length of v as an unsigned varint.
+func uvarintLen(v uint64) int {
+	n := 1
+	for v >= 0x80 {
+		v >>= 7
+		n++
+	}
+	return n
+}
+
+// ReadUvarint consumes an unsigned varint. It returns io.ErrUnexpectedEOF,
+// consuming nothing, if the buffer ends mid-varint, and ErrVarintOverflow if
+// the value exceeds 64 bits.
+func (b *Buffer) ReadUvarint() (uint64, error) {
+	v, n := binary.Uvarint(b.Bytes())
+	if err := b.consumeVarint(n); err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+// ReadVarint is like ReadUvarint for zig-zag encoded signed varints.
+func (b *Buffer) ReadVarint() (int64, error) {
+	v, n := binary.Varint(b.Bytes())
+	if err := b.consumeVarint(n); err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+// ReadUvarints decodes n consecutive unsigned varints, such as a protobuf
+// packed repeated field. On error it consumes nothing and returns
+// io.ErrUnexpectedEOF if the buffer ends first, or ErrVarintOverflow.
+func (b *Buffer) ReadUvarints(n int) ([]uint64, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	out := make([]uint64, 0, n)
+	src := b.Bytes()
+	off := 0
+	for len(out) < n {
+		v, m := binary.Uvarint(src[off:])
+		if m <= 0 {
+			return nil, varintErr(m)
+		}
+		out = append(out, v)
+		off += m
+	}
+	b.Next(off)
+	return out, nil
+}
+
+// ReadAllUvarints decodes unsigned varints until the buffer is drained. On
+// error it consumes nothing, as with ReadUvarints.
+func (b *Buffer) ReadAllUvarints() ([]uint64, error) {
+	var out []uint64
+	src := b.Bytes()
+	for off := 0; off < len(src); {
+		v, m := binary.Uvarint(src[off:])
+		if m <= 0 {
+			return nil, varintErr(m)
+		}
+		out = append(out, v)
+		off += m
+	}
+	b.Next(len(src))
+	return out, nil
+}
+
+// consumeVarint advances past a decoded varint using the n reported by
+// encoding/binary: 0 means more input is needed, negative means overflow.
+func (b *Buffer) consumeVarint(n int) error {
+	if n <= 0 {
+		return varintErr(n)
+	}
+	b.Next(n)
+	return nil
+}
+
+// varintErr maps a non-positive n from encoding/binary to an error.
+func varintErr(n int) error {
+	if n == 0 {
+		return io.ErrUnexpectedEOF
+	}
+	return ErrVarintOverflow
+}
+
+// PrefixFormat selects how a length prefix is encoded.
+type PrefixFormat uint8
+
+const (
+	// PrefixUvarint encodes the length as an unsigned varint.
+	PrefixUvarint PrefixFormat = iota
+	// PrefixUint32BE encodes the length as a 4-byte big-endian integer.
+	PrefixUint32BE
+	// PrefixUint32LE encodes the length as a 4-byte little-endian integer.
+	PrefixUint32LE
+)
+
+// ErrPrefixFormat is returned for an unknown PrefixFormat or a length that does
+// not fit in the selected prefix.
+var ErrPrefixFormat = errors.New("gobuff: invalid length prefix")
+
+// WriteLengthPrefixedString appends len(s) in format f followed by s, and
+// returns the total number of bytes written. If the whole frame would exceed
+// the SetMaxSize limit it returns ErrBufferFull and writes nothing.
+func (b *Buffer) WriteLengthPrefixedString(s string, f PrefixFormat) (int, error) {
+	var n int
+	switch f {
+	case PrefixUvarint:
+		n = uvarintLen(uint64(len(s)))
+	case PrefixUint32BE, PrefixUint32LE:
+		if uint64(len(s)) > 1<<32-1 {
+			return 0, ErrPrefixFormat
+		}
+		n = 4
+	default:
+		return 0, ErrPrefixFormat
+	}
+	if b.exceeds(n + len(s)) {
+		return 0, ErrBufferFull
+	}
+	if f == PrefixUvarint {
+		_, _ = b.WriteUvarint(uint64(len(s)))
+	} else {
+		_ = b.WriteUint32(f.order(), uint32(len(s)))
+	}
+	m, _ := b.WriteString(s)
+	return n + m, nil
+}
+
+// ReadLengthPrefixedString consumes a length in format f and that many bytes,
+// returning them as a newly allocated string. If the prefix or the data is
+// truncated it consumes nothing and returns io.ErrUnexpectedEOF.
+func (b *Buffer) ReadLengthPrefixedString(f PrefixFormat) (string, error) {
+	src := b.Bytes()
+	var length uint64
+	var n int
+	switch f {
+	case PrefixUvarint:
+		length, n = binary.Uvarint(src)
+		if n <= 0 {
+			return "", varintErr(n)
+		}
+	case PrefixUint32BE, PrefixUint32LE:
+		if len(src) < 4 {
+			return "", io.ErrUnexpectedEOF
+		}
+		length, n = uint64(f.order().Uint32(src)), 4
+	default:
+		return "", ErrPrefixFormat
+	}
+	if length > uint64(len(src)-n) {
+		return "", io.ErrUnexpectedEOF
+	}
+	s := string(src[n : n+int(length)])
+	b.Next(n + int(length))
+	return s, nil
+}
+
+func (f PrefixFormat) order() binary.ByteOrder {
+	if f == PrefixUint32LE {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
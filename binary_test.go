@@ -0,0 +1,205 @@
+package gobuff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBufferFixedWidthIntegers(t *testing.T) {
+	b := NewBuffer(0)
+	b.WriteUint16(binary.BigEndian, 0x0102)
+	b.WriteUint32(binary.LittleEndian, 0x03040506)
+	b.WriteUint64(binary.BigEndian, 0x0708090a0b0c0d0e)
+	want := []byte{1, 2, 6, 5, 4, 3, 7, 8, 9, 10, 11, 12, 13, 14}
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Fatalf("unexpected encoding: %v", b.Bytes())
+	}
+
+	v16, err := b.ReadUint16(binary.BigEndian)
+	if err != nil || v16 != 0x0102 {
+		t.Fatalf("ReadUint16 v=%#x err=%v", v16, err)
+	}
+	v32, err := b.ReadUint32(binary.LittleEndian)
+	if err != nil || v32 != 0x03040506 {
+		t.Fatalf("ReadUint32 v=%#x err=%v", v32, err)
+	}
+	v64, err := b.ReadUint64(binary.BigEndian)
+	if err != nil || v64 != 0x0708090a0b0c0d0e {
+		t.Fatalf("ReadUint64 v=%#x err=%v", v64, err)
+	}
+
+	_, _ = b.Write([]byte{1, 2, 3})
+	if _, err := b.ReadUint32(binary.BigEndian); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected ErrUnexpectedEOF, got %v", err)
+	}
+	if b.Len() != 3 {
+		t.Fatalf("short read consumed data: len=%d", b.Len())
+	}
+}
+
+func TestBufferFixedWidthIntegersNoAlloc(t *testing.T) {
+	b := NewBuffer(64)
+	allocs := testing.AllocsPerRun(100, func() {
+		b.Reset()
+		b.WriteUint32(binary.BigEndian, 42)
+		_, _ = b.ReadUint32(binary.BigEndian)
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %v", allocs)
+	}
+}
+
+func TestBufferVarints(t *testing.T) {
+	b := NewBuffer(0)
+	values := []uint64{0, 1, 127, 128, 300, 1<<63 + 5}
+	for _, v := range values {
+		if n, _ := b.WriteUvarint(v); n != len(binary.AppendUvarint(nil, v)) {
+			t.Fatalf("WriteUvarint(%d) wrote %d bytes", v, n)
+		}
+	}
+	for _, want := range values {
+		got, err := b.ReadUvarint()
+		if err != nil || got != want {
+			t.Fatalf("ReadUvarint got %d err=%v, want %d", got, err, want)
+		}
+	}
+
+	for _, v := range []int64{0, -1, 63, -64, -1 << 63} {
+		_, _ = b.WriteVarint(v)
+		if got, err := b.ReadVarint(); err != nil || got != v {
+			t.Fatalf("Varint round trip %d: got %d err=%v", v, got, err)
+		}
+	}
+}
+
+func TestBufferUvarintErrors(t *testing.T) {
+	b := NewBuffer(0)
+	_, _ = b.Write([]byte{0x80, 0x80})
+	if _, err := b.ReadUvarint(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected ErrUnexpectedEOF, got %v", err)
+	}
+	if b.Len() != 2 {
+		t.Fatalf("truncated varint consumed data: len=%d", b.Len())
+	}
+
+	b.Reset()
+	_, _ = b.Write(bytes.Repeat([]byte{0xff}, 10))
+	_ = b.WriteByte(0x01)
+	if _, err := b.ReadUvarint(); err != ErrVarintOverflow {
+		t.Fatalf("expected ErrVarintOverflow, got %v", err)
+	}
+}
+
+func TestBufferReadUvarints(t *testing.T) {
+	// Packed encoding of [3, 270, 86942] from the protobuf encoding guide.
+	packed := []byte{0x03, 0x8e, 0x02, 0x9e, 0xa7, 0x05}
+	want := []uint64{3, 270, 86942}
+
+	b := NewBuffer(0)
+	_, _ = b.Write(packed)
+	_ = b.WriteByte(0x7f)
+	got, err := b.ReadUvarints(3)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("ReadUvarints = %v, %v; want %v", got, err, want)
+	}
+	if b.Len() != 1 {
+		t.Fatalf("expected trailing byte to remain, len=%d", b.Len())
+	}
+
+	b.Reset()
+	_, _ = b.Write(packed)
+	got, err = b.ReadAllUvarints()
+	if err != nil || !reflect.DeepEqual(got, want) || b.Len() != 0 {
+		t.Fatalf("ReadAllUvarints = %v, %v (len %d); want %v", got, err, b.Len(), want)
+	}
+}
+
+func TestBufferReadUvarintsErrors(t *testing.T) {
+	truncated := []byte{0x03, 0x8e, 0x02, 0x9e, 0xa7}
+
+	b := NewBuffer(0)
+	_, _ = b.Write(truncated)
+	if _, err := b.ReadUvarints(3); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected ErrUnexpectedEOF, got %v", err)
+	}
+	if _, err := b.ReadUvarints(4); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected ErrUnexpectedEOF when asking for too many, got %v", err)
+	}
+	if _, err := b.ReadAllUvarints(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected ErrUnexpectedEOF, got %v", err)
+	}
+	if b.Len() != len(truncated) {
+		t.Fatalf("failed decode consumed data: len=%d", b.Len())
+	}
+
+	b.Reset()
+	_ = b.WriteByte(0x01)
+	_, _ = b.Write(bytes.Repeat([]byte{0xff}, 10))
+	_ = b.WriteByte(0x01)
+	if _, err := b.ReadAllUvarints(); err != ErrVarintOverflow {
+		t.Fatalf("expected ErrVarintOverflow, got %v", err)
+	}
+}
+
+func TestBufferLengthPrefixedString(t *testing.T) {
+	formats := []struct {
+		name   string
+		f      PrefixFormat
+		prefix int
+	}{
+		{"uvarint", PrefixUvarint, 2},
+		{"uint32be", PrefixUint32BE, 4},
+		{"uint32le", PrefixUint32LE, 4},
+	}
+	long := strings.Repeat("é", 100) // 200 bytes: a two-byte uvarint
+	for _, tc := range formats {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBuffer(0)
+			n, err := b.WriteLengthPrefixedString(long, tc.f)
+			if err != nil || n != tc.prefix+len(long) {
+				t.Fatalf("Write = %d, %v", n, err)
+			}
+			_, _ = b.WriteLengthPrefixedString("", tc.f)
+			got, err := b.ReadLengthPrefixedString(tc.f)
+			if err != nil || got != long {
+				t.Fatalf("Read = %q, %v", got, err)
+			}
+			if got, err := b.ReadLengthPrefixedString(tc.f); err != nil || got != "" {
+				t.Fatalf("Read empty = %q, %v", got, err)
+			}
+			if b.Len() != 0 {
+				t.Fatalf("expected buffer drained, len=%d", b.Len())
+			}
+		})
+	}
+}
+
+func TestBufferLengthPrefixedStringTruncated(t *testing.T) {
+	b := NewBuffer(0)
+	_, _ = b.WriteLengthPrefixedString("hello", PrefixUint32BE)
+	b.Truncate(b.Len() - 1)
+	if _, err := b.ReadLengthPrefixedString(PrefixUint32BE); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected ErrUnexpectedEOF for short data, got %v", err)
+	}
+	if b.Len() != 8 {
+		t.Fatalf("truncated read consumed data: len=%d", b.Len())
+	}
+
+	b.Reset()
+	_, _ = b.Write([]byte{0x00, 0x00})
+	if _, err := b.ReadLengthPrefixedString(PrefixUint32LE); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected ErrUnexpectedEOF for short prefix, got %v", err)
+	}
+	b.Reset()
+	_ = b.WriteByte(0x80)
+	if _, err := b.ReadLengthPrefixedString(PrefixUvarint); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected ErrUnexpectedEOF for truncated varint, got %v", err)
+	}
+	if _, err := b.ReadLengthPrefixedString(PrefixFormat(99)); err != ErrPrefixFormat {
+		t.Fatalf("expected ErrPrefixFormat, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package gobuff
+
+import "runtime"
+
+// chargeBudget reserves cap(b.buf) of the CapacityBudget for b before it is
+// retained, reporting false when that would exceed the budget.
+func (p *BufferPool) chargeBudget(b *Buffer) bool {
+	c := int64(cap(b.buf))
+	for {
+		cur := p.pooledCap.Load()
+		if cur+c > p.budget {
+			return false
+		}
+		if p.pooledCap.CompareAndSwap(cur, cur+c) {
+			b.budgeted = c
+			return true
+		}
+	}
+}
+
+// refundBudget returns the capacity charged for b once it leaves the pool.
+func (p *BufferPool) refundBudget(b *Buffer) {
+	if b.budgeted > 0 {
+		p.pooledCap.Add(-b.budgeted)
+		b.budgeted = 0
+	}
+}
+
+// trackCollected arranges for b's charge to be refunded if the GC frees it
+// from a sync.Pool, which drops idle buffers without telling the pool.
+func (p *BufferPool) trackCollected(b *Buffer) {
+	if b.budgeted > 0 {
+		runtime.SetFinalizer(b, p.refundBudget)
+	}
+}
+
+// untrackCollected undoes trackCollected for a buffer leaving the pool.
+func (p *BufferPool) untrackCollected(b *Buffer) {
+	if b.budgeted > 0 && !p.bounded {
+		runtime.SetFinalizer(b, nil)
+	}
+}
+
+// refundList empties fl, refunding the charge of every buffer it held.
+func (p *BufferPool) refundList(fl *freelist) {
+	fl.drain(p.refundBudget)
+}
@@ -1,16 +1,85 @@
 package gobuff
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"io"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"syscall"
+	"unicode/utf8"
+	"unsafe"
+)
+
+var (
+	// ErrRecordSize is returned when the unread length is not a whole number of records.
+	ErrRecordSize = errors.New("gobuff: length is not a multiple of record size")
+	// ErrNegativeOffset is returned by ReadAt and WriteAt for offsets below zero.
+	ErrNegativeOffset = errors.New("gobuff: negative offset")
+	// ErrUnreadByte is returned by UnreadByte when the previous operation was not a read.
+	ErrUnreadByte = errors.New("gobuff: UnreadByte: previous operation was not a successful read")
+	// ErrJSONNotString is returned by UnmarshalJSON for JSON values other than a string or null.
+	ErrJSONNotString = errors.New("gobuff: Buffer.UnmarshalJSON: expected a base64 string")
+	// ErrBufferFull is returned when a write or ReadFrom would take the buffer
+	// past the limit set with SetMaxSize.
+	ErrBufferFull = errors.New("gobuff: buffer size limit exceeded")
+	// ErrInvalidWhence is returned by Seek for an unknown whence value.
+	ErrInvalidWhence = errors.New("gobuff: Seek: invalid whence")
+	// ErrDrainOffset is returned by SeekDrain for offsets past the retained contents.
+	ErrDrainOffset = errors.New("gobuff: drain offset beyond retained contents")
 )
 
 // Buffer is a reusable byte buffer with explicit growth strategy.
 // It keeps a read cursor (r) so repeated Read calls work as expected.
 type Buffer struct {
-	buf []byte
-	r   int
+	buf      []byte
+	r        int
+	lastRead int // bytes consumed by the last ReadByte-style call; 0 disables UnreadByte
+
+	scratch     [scratchSize]byte
+	scratchBusy bool
+	growth      GrowthStrategy
+	growthHint  int
+	growthLimit int            // GrowDampened threshold; 0 means DefaultGrowthThreshold
+	resumable   bool           // keep consumed bytes so SeekDrain can revisit them
+	pooled      bool           // held by a pool; maintained only under DebugLeakDetection
+	appendOnly  bool           // Write, WriteByte and WriteString append directly
+	stable      bool           // never compact, so unread bytes do not move in place
+	maxSize     int            // limit on unread bytes for error-returning writes; 0 means none
+	fields      map[string]int // non-nil only while field tracking is enabled
+	alloc       Allocator      // source of backing arrays; nil uses make
+	budgeted    int64          // capacity charged to a pool's CapacityBudget while idle
+	class       int            // pool size class it was last handed out for; 0 when unknown
 }
 
+// GrowthStrategy selects how a Buffer sizes its backing array when it must reallocate.
+type GrowthStrategy uint8
+
+const (
+	// GrowPowerOfTwo rounds the required capacity up to the next power of two (default).
+	GrowPowerOfTwo GrowthStrategy = iota
+	// GrowExact allocates exactly the required capacity.
+	GrowExact
+	// GrowFactor1_5 grows the current capacity by 1.5x, or to the required size if larger.
+	GrowFactor1_5
+	// GrowDampened rounds up to the next power of two while the required
+	// capacity is at most the growth threshold, and past it grows the current
+	// capacity by 1.25x (or to the required size if larger) to limit waste on
+	// large buffers.
+	GrowDampened
+)
+
+// DefaultGrowthThreshold is the capacity above which GrowDampened stops
+// doubling, unless overridden with SetGrowthThreshold.
+const DefaultGrowthThreshold = 32 << 10
+
+// scratchSize is large enough for any fixed-width integer or varint encoding.
+const scratchSize = 16
+
 // NewBuffer creates a buffer with an optional initial capacity.
 func NewBuffer(initialCap int) *Buffer {
 	if initialCap < 0 {
@@ -19,11 +88,172 @@ func NewBuffer(initialCap int) *Buffer {
 	return &Buffer{buf: make([]byte, 0, initialCap)}
 }
 
+// NewBufferPattern creates a buffer holding totalLen bytes made by repeating
+// pattern, with a final partial copy if totalLen is not a multiple of its
+// length. The fill doubles the copied region on each step. An empty pattern
+// or non-positive totalLen yields an empty buffer.
+func NewBufferPattern(pattern []byte, totalLen int) *Buffer {
+	if totalLen <= 0 || len(pattern) == 0 {
+		return NewBuffer(0)
+	}
+	buf := make([]byte, totalLen)
+	n := copy(buf, pattern)
+	for n < totalLen {
+		n += copy(buf[n:], buf[:n])
+	}
+	return &Buffer{buf: buf}
+}
+
+// NewAppendBuffer creates an append-only buffer for write accumulation. Write,
+// WriteByte and WriteString append directly, skipping all cursor and reset
+// logic, and the buffer is never emptied implicitly: only Reset clears it.
+// Reads still work but leave consumed bytes in place, as with SetResumable.
+func NewAppendBuffer(initialCap int) *Buffer {
+	b := NewBuffer(initialCap)
+	b.appendOnly = true
+	b.resumable = true
+	return b
+}
+
+// Clone returns an independent Buffer holding a copy of the unread bytes, with
+// capacity rounded up to a power of two. Writes to either buffer do not affect
+// the other. The growth strategy is carried over; the read cursor starts at 0.
+func (b *Buffer) Clone() *Buffer {
+	n := b.Len()
+	c := &Buffer{
+		buf:         make([]byte, n, nextPowerOfTwo(n)),
+		growth:      b.growth,
+		growthLimit: b.growthLimit,
+	}
+	copy(c.buf, b.buf[b.r:])
+	return c
+}
+
+// SetGrowthStrategy changes how future reallocations size the backing array.
+func (b *Buffer) SetGrowthStrategy(s GrowthStrategy) {
+	b.growth = s
+}
+
+// SetGrowthThreshold sets the capacity above which GrowDampened switches from
+// power-of-two to 1.25x growth. Values <= 0 restore DefaultGrowthThreshold.
+func (b *Buffer) SetGrowthThreshold(n int) {
+	if n < 0 {
+		n = 0
+	}
+	b.growthLimit = n
+}
+
+// SetGrowthHint tells the next reallocation that the buffer is expected to
+// hold about expectedFinalSize unread bytes, so it can allocate that much
+// instead of following the growth strategy. The hint is advisory and is
+// cleared after the next reallocation; values <= 0 clear it.
+func (b *Buffer) SetGrowthHint(expectedFinalSize int) {
+	if expectedFinalSize < 0 {
+		expectedFinalSize = 0
+	}
+	b.growthHint = expectedFinalSize
+}
+
 // Bytes returns the unread contents of the buffer.
 func (b *Buffer) Bytes() []byte {
 	return b.buf[b.r:]
 }
 
+// EqualBytes reports whether the unread contents equal p.
+// It does not allocate or move the read cursor.
+func (b *Buffer) EqualBytes(p []byte) bool {
+	return bytes.Equal(b.Bytes(), p)
+}
+
+// Equal reports whether the unread contents equal other, like EqualBytes. It
+// does not copy, move the read cursor, or compact the buffer.
+func (b *Buffer) Equal(other []byte) bool {
+	return bytes.Equal(b.Bytes(), other)
+}
+
+// Compare compares the unread contents with other lexicographically, returning
+// -1, 0 or +1 as bytes.Compare does. It does not copy, move the read cursor,
+// or compact the buffer.
+func (b *Buffer) Compare(other []byte) int {
+	return bytes.Compare(b.Bytes(), other)
+}
+
+// EqualIgnoringSuffix reports whether the unread contents equal p once a
+// single trailing suffix (such as "\n") is stripped from each side where
+// present. It does not allocate, mutate the buffer, or move the read cursor.
+func (b *Buffer) EqualIgnoringSuffix(p, suffix []byte) bool {
+	return bytes.Equal(bytes.TrimSuffix(b.Bytes(), suffix), bytes.TrimSuffix(p, suffix))
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, returning a copy of the
+// unread bytes that stays valid after the buffer is reused.
+func (b *Buffer) MarshalBinary() ([]byte, error) {
+	return bytes.Clone(b.Bytes()), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler by resetting the
+// buffer and writing data into it. data is copied, not retained.
+func (b *Buffer) UnmarshalBinary(data []byte) error {
+	b.Reset()
+	_, err := b.Write(data)
+	return err
+}
+
+// MarshalJSON implements json.Marshaler, encoding the unread bytes as a
+// base64 string (standard encoding, padded). The result is a new slice and
+// does not alias the buffer.
+func (b *Buffer) MarshalJSON() ([]byte, error) {
+	src := b.Bytes()
+	out := make([]byte, base64.StdEncoding.EncodedLen(len(src))+2)
+	out[0], out[len(out)-1] = '"', '"'
+	base64.StdEncoding.Encode(out[1:len(out)-1], src)
+	return out, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, resetting the buffer and decoding
+// a base64 JSON string into its existing storage, growing it only if needed.
+// A JSON null leaves the buffer unchanged.
+func (b *Buffer) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) < 2 || data[0] != '"' || data[len(data)-1] != '"' {
+		return ErrJSONNotString
+	}
+	src := data[1 : len(data)-1]
+	if bytes.IndexByte(src, '\\') >= 0 {
+		// Escaped characters are legal JSON but rare here; let encoding/json
+		// unescape them.
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		src = []byte(s)
+	}
+	b.Reset()
+	n, err := base64.StdEncoding.Decode(b.Reserve(base64.StdEncoding.DecodedLen(len(src))), src)
+	if err != nil {
+		b.Reset()
+		return err
+	}
+	b.Truncate(n)
+	return nil
+}
+
+// DrainTo returns a newly allocated copy of the unread bytes and consumes
+// them, leaving the buffer empty. It is the canonical way to take ownership of
+// the contents before recycling the buffer: unlike Bytes, the result does not
+// alias pooled storage and stays valid after Put.
+//
+//	data := buf.DrainTo()
+//	pool.Put(buf)
+func (b *Buffer) DrainTo() []byte {
+	out := bytes.Clone(b.Bytes())
+	b.r = len(b.buf)
+	b.rewind()
+	return out
+}
+
 // UnsafeBytes exposes the full underlying slice (including consumed bytes).
 // Use only when you need zero-copy access; mutations affect the buffer.
 func (b *Buffer) UnsafeBytes() []byte {
@@ -35,6 +265,61 @@ func (b *Buffer) String() string {
 	return string(b.Bytes())
 }
 
+// goStringPreview is how many unread bytes GoString shows.
+const goStringPreview = 16
+
+// GoString implements fmt.GoStringer with a concise summary for %#v, such as
+// gobuff.Buffer{len:4096 cap:8192 r:128 head:68 65 6c 6c 6f |hello|...},
+// previewing at most the first 16 unread bytes in hex and ASCII.
+func (b *Buffer) GoString() string {
+	p := b.Bytes()
+	more := len(p) > goStringPreview
+	if more {
+		p = p[:goStringPreview]
+	}
+	out := make([]byte, 0, 64+4*len(p))
+	out = append(out, "gobuff.Buffer{len:"...)
+	out = strconv.AppendInt(out, int64(b.Len()), 10)
+	out = append(out, " cap:"...)
+	out = strconv.AppendInt(out, int64(cap(b.buf)), 10)
+	out = append(out, " r:"...)
+	out = strconv.AppendInt(out, int64(b.r), 10)
+	out = append(out, " head:"...)
+	const hexDigits = "0123456789abcdef"
+	for i, c := range p {
+		if i > 0 {
+			out = append(out, ' ')
+		}
+		out = append(out, hexDigits[c>>4], hexDigits[c&0x0f])
+	}
+	out = append(out, " |"...)
+	for _, c := range p {
+		if c < 0x20 || c > 0x7e {
+			c = '.'
+		}
+		out = append(out, c)
+	}
+	out = append(out, '|')
+	if more {
+		out = append(out, "..."...)
+	}
+	out = append(out, '}')
+	return string(out)
+}
+
+// StringUnsafe returns the unread contents as a string that aliases the
+// buffer's memory, avoiding the copy made by String (as strings.Builder does).
+// The result is only valid until the next write, Reset, or Put; after that its
+// contents may change, breaking Go's string immutability guarantee.
+// Prefer String unless the copy shows up in profiles.
+func (b *Buffer) StringUnsafe() string {
+	p := b.Bytes()
+	if len(p) == 0 {
+		return ""
+	}
+	return unsafe.String(unsafe.SliceData(p), len(p))
+}
+
 // Len returns the number of unread bytes.
 func (b *Buffer) Len() int {
 	return len(b.buf) - b.r
@@ -45,6 +330,58 @@ func (b *Buffer) Cap() int {
 	return cap(b.buf)
 }
 
+// Available returns how many bytes can be appended without growing or
+// compacting the buffer.
+func (b *Buffer) Available() int {
+	return cap(b.buf) - len(b.buf)
+}
+
+// AvailableBuffer returns an empty slice over the buffer's spare capacity for
+// allocation-free appends, as with bytes.Buffer:
+//
+//	b.CommitAvailable(strconv.AppendInt(b.AvailableBuffer(), n, 10))
+//
+// The slice is only valid until the next buffer modification; any write,
+// growth, or compaction (which moves unread bytes to the front) invalidates it.
+// Call Grow first to guarantee room so the append does not reallocate.
+func (b *Buffer) AvailableBuffer() []byte {
+	return b.buf[len(b.buf):len(b.buf)]
+}
+
+// CommitAvailable adds p, usually the result of appending to AvailableBuffer,
+// to the buffer. If p still starts in the buffer's spare capacity its bytes are
+// already in place and only the length is extended; otherwise (for example
+// when the append reallocated) p is copied in with Write.
+func (b *Buffer) CommitAvailable(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if len(p) <= b.Available() && unsafe.SliceData(p) == unsafe.SliceData(b.buf[len(b.buf):cap(b.buf)]) {
+		if b.exceeds(len(p)) {
+			return 0, ErrBufferFull
+		}
+		b.buf = b.buf[:len(b.buf)+len(p)]
+		return len(p), nil
+	}
+	return b.Write(p)
+}
+
+// WillReallocate reports whether writing n more bytes would allocate a new
+// backing array. Space held by already-read bytes counts as available, since
+// the write would reclaim it by compacting in place, except for stable,
+// resumable and append-only buffers, which never compact. It has no side
+// effects.
+func (b *Buffer) WillReallocate(n int) bool {
+	if n <= 0 {
+		return false
+	}
+	used := b.Len()
+	if b.resumable || b.appendOnly || (b.stable && b.r < len(b.buf)) {
+		used = len(b.buf)
+	}
+	return used+n > cap(b.buf)
+}
+
 // Grow ensures the buffer can accommodate n additional bytes.
 func (b *Buffer) Grow(n int) {
 	if n > 0 {
@@ -52,6 +389,16 @@ func (b *Buffer) Grow(n int) {
 	}
 }
 
+// GrowForChunks grows the buffer once so that writing all of chunks afterwards
+// will not reallocate.
+func (b *Buffer) GrowForChunks(chunks ...[]byte) {
+	n := 0
+	for _, c := range chunks {
+		n += len(c)
+	}
+	b.Grow(n)
+}
+
 // Reserve grows the buffer and returns a slice of length n backed by the buffer
 // for zero-copy writes. The caller must not let the returned slice escape
 // beyond the buffer's lifetime without Put-ing the buffer back to a pool.
@@ -65,19 +412,236 @@ func (b *Buffer) Reserve(n int) []byte {
 	return b.buf[prev:]
 }
 
+// ReserveAligned is like Reserve but first pads the buffer with zero bytes so
+// the returned slice starts at a multiple of align from the start of the
+// backing array (the offsets used by ReadAt). Alignment is relative to that
+// base, which is only as aligned as the Go allocator makes it; check the
+// address when hardware alignment matters. A later growth or compaction moves
+// the data and may break alignment. align <= 1 behaves like Reserve.
+func (b *Buffer) ReserveAligned(n, align int) []byte {
+	if n <= 0 {
+		return nil
+	}
+	if align <= 1 {
+		return b.Reserve(n)
+	}
+	// Grow once for the worst-case padding so padding is computed against the
+	// final layout.
+	b.grow(n + align - 1)
+	pad := (align - len(b.buf)%align) % align
+	start := len(b.buf) + pad
+	b.buf = b.buf[:start+n]
+	clear(b.buf[start-pad : start])
+	return b.buf[start:]
+}
+
 // Reset clears the buffer to empty.
 func (b *Buffer) Reset() {
 	b.buf = b.buf[:0]
 	b.r = 0
+	b.lastRead = 0
+	if b.fields != nil {
+		clear(b.fields)
+	}
+}
+
+// resetModes restores every per-use setting (SetStable, SetResumable, append
+// mode, SetMaxSize, TrackFields, SetGrowthHint and the growth strategy) to its
+// default, so a pooled buffer does not carry them to its next user.
+func (b *Buffer) resetModes() {
+	b.resumable, b.appendOnly, b.stable = false, false, false
+	b.maxSize = 0
+	b.fields = nil
+	b.growth, b.growthHint, b.growthLimit = GrowPowerOfTwo, 0, 0
+}
+
+// ResetAndFree empties the buffer and drops its backing array so the GC can
+// reclaim it, for long-lived buffers outside a pool after an occasional spike.
+// With an Allocator the array is passed to its Free instead. The next write
+// allocates afresh. Prefer Reset on pooled hot paths.
+func (b *Buffer) ResetAndFree() {
+	b.Reset()
+	b.freeBuf(b.buf)
+	b.buf = nil
+}
+
+// Truncate keeps the first n unread bytes and discards the rest, retaining
+// the read cursor and capacity. It panics if n is negative or greater than Len.
+func (b *Buffer) Truncate(n int) {
+	if n < 0 || n > b.Len() {
+		panic("gobuff.Buffer: truncation out of range")
+	}
+	b.buf = b.buf[:b.r+n]
+	b.lastRead = 0
+}
+
+// rewind empties the buffer once all data has been consumed. Unlike Reset it
+// keeps per-buffer state such as recorded field offsets. Resumable buffers
+// keep their consumed bytes and only clear lastRead.
+func (b *Buffer) rewind() {
+	b.lastRead = 0
+	if b.resumable {
+		return
+	}
+	b.buf = b.buf[:0]
+	b.r = 0
 }
 
+// SetResumable makes the buffer retain consumed bytes, disabling the automatic
+// reset on drain and the compaction that reclaims space before growing, so
+// that SeekDrain can return to any offset recorded with DrainOffset. Reset
+// still empties the buffer.
+func (b *Buffer) SetResumable(on bool) {
+	b.resumable = on
+}
+
+// SetMaxSize caps how many unread bytes the buffer may hold, for buffers fed
+// from untrusted input. Write, WriteByte, WriteString, WriteRune, WriteStrings,
+// AppendBytes, WritePattern, ConcatFrom, WithScratch and the binary writers
+// (WriteUint16/32/64, WriteUvarint, WriteVarint, WriteLengthPrefixedString)
+// then return ErrBufferFull, writing nothing, when they would exceed it.
+// ReadFrom stops with ErrBufferFull once the buffer is full and the source
+// may have more; a source that is an io.ByteScanner is checked for EOF
+// without losing data, so one that exactly fits succeeds. Growth never
+// allocates more than n bytes for such writes. Methods without an error
+// result, such as Reserve, are not limited. n <= 0 removes the limit.
+func (b *Buffer) SetMaxSize(n int) {
+	if n < 0 {
+		n = 0
+	}
+	b.maxSize = n
+}
+
+// exceeds reports whether adding n unread bytes would break the SetMaxSize limit.
+func (b *Buffer) exceeds(n int) bool {
+	return b.maxSize > 0 && b.Len()+n > b.maxSize
+}
+
+// SetStable disables the compaction grow uses to reclaim consumed space, so
+// slices returned by Bytes, Next or Peek keep their contents across later
+// writes: growth either appends in place or moves to a new array, leaving the
+// old one untouched. The trade-off is memory: consumed bytes are not reclaimed
+// until the buffer drains completely or is Reset, so growth may reallocate
+// where compaction would have sufficed. The default is off.
+func (b *Buffer) SetStable(on bool) {
+	b.stable = on
+}
+
+// DrainOffset returns how far the contents have been consumed, as an offset
+// from the start of the buffer (the same offsets as ReadAt). Persist it to
+// resume a partially sent buffer with SeekDrain.
+func (b *Buffer) DrainOffset() int64 {
+	return int64(b.r)
+}
+
+// Seek implements io.Seeker by moving the read cursor relative to the start
+// of the buffered contents (the same offsets as ReadAt), the cursor, or the
+// end. Results past the end are clamped to it; negative results return
+// ErrNegativeOffset without moving the cursor. Seek enables SetResumable so
+// that reads no longer discard data the caller may seek back to.
+func (b *Buffer) Seek(offset int64, whence int) (int64, error) {
+	var base int64
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		base = int64(b.r)
+	case io.SeekEnd:
+		base = int64(len(b.buf))
+	default:
+		return int64(b.r), ErrInvalidWhence
+	}
+	pos := base + offset
+	if pos < 0 {
+		return int64(b.r), ErrNegativeOffset
+	}
+	if pos > int64(len(b.buf)) {
+		pos = int64(len(b.buf))
+	}
+	b.resumable = true
+	b.r = int(pos)
+	b.lastRead = 0
+	return pos, nil
+}
+
+// SeekDrain moves the read cursor to offset, a value previously returned by
+// DrainOffset, so that draining resumes from that checkpoint. The bytes must
+// still be present: either the buffer is resumable, or it has been refilled
+// with the original contents (for example after a restart). It returns
+// ErrNegativeOffset or ErrDrainOffset for offsets outside the contents.
+func (b *Buffer) SeekDrain(offset int64) error {
+	if offset < 0 {
+		return ErrNegativeOffset
+	}
+	if offset > int64(len(b.buf)) {
+		return ErrDrainOffset
+	}
+	b.r = int(offset)
+	b.lastRead = 0
+	return nil
+}
+
+// TrackFields enables or disables recording of MarkField offsets.
+// It is a debugging aid for binary encoders; disabling drops recorded offsets.
+func (b *Buffer) TrackFields(on bool) {
+	if !on {
+		b.fields = nil
+		return
+	}
+	if b.fields == nil {
+		b.fields = make(map[string]int)
+	}
+}
+
+// MarkField records the current write offset (an index into UnsafeBytes) under
+// name. It is a no-op unless field tracking is enabled.
+func (b *Buffer) MarkField(name string) {
+	if b.fields != nil {
+		b.fields[name] = len(b.buf)
+	}
+}
+
+// FieldOffsets returns a copy of the offsets recorded by MarkField, or nil when
+// field tracking is disabled. Offsets are cleared by Reset and may be invalidated
+// when consumed bytes are compacted away.
+func (b *Buffer) FieldOffsets() map[string]int {
+	if b.fields == nil {
+		return nil
+	}
+	out := make(map[string]int, len(b.fields))
+	for k, v := range b.fields {
+		out[k] = v
+	}
+	return out
+}
+
+// largeWriteFactor marks a Write as large when it exceeds this multiple of the
+// current capacity; under the default GrowPowerOfTwo strategy such writes skip
+// power-of-two growth. Other strategies always size through nextCap.
+const largeWriteFactor = 4
+
 // Write appends p to the buffer.
 func (b *Buffer) Write(p []byte) (int, error) {
+	if b.exceeds(len(p)) {
+		return 0, ErrBufferFull
+	}
+	if b.appendOnly {
+		b.buf = append(b.buf, p...)
+		return len(p), nil
+	}
 	if len(p) == 0 {
 		return 0, nil
 	}
 	if b.r >= len(b.buf) {
-		b.Reset()
+		b.rewind()
+	}
+	if len(p) > largeWriteFactor*cap(b.buf) && b.growth == GrowPowerOfTwo && b.growthHint == 0 && !b.resumable && b.alloc == nil {
+		// Large write: allocate once for unread+p and copy both in a single
+		// append instead of zeroing a doubled array and appending afterwards.
+		unread := b.buf[b.r:len(b.buf):len(b.buf)]
+		b.buf = append(unread, p...)
+		b.r = 0
+		b.lastRead = 0
+		return len(p), nil
 	}
 	b.grow(len(p))
 	b.buf = append(b.buf, p...)
@@ -86,8 +650,15 @@ func (b *Buffer) Write(p []byte) (int, error) {
 
 // WriteByte appends a single byte.
 func (b *Buffer) WriteByte(v byte) error {
+	if b.exceeds(1) {
+		return ErrBufferFull
+	}
+	if b.appendOnly {
+		b.buf = append(b.buf, v)
+		return nil
+	}
 	if b.r >= len(b.buf) {
-		b.Reset()
+		b.rewind()
 	}
 	b.grow(1)
 	b.buf = append(b.buf, v)
@@ -96,39 +667,617 @@ func (b *Buffer) WriteByte(v byte) error {
 
 // WriteString appends a string to the buffer.
 func (b *Buffer) WriteString(s string) (int, error) {
+	if b.exceeds(len(s)) {
+		return 0, ErrBufferFull
+	}
+	if b.appendOnly {
+		b.buf = append(b.buf, s...)
+		return len(s), nil
+	}
 	if len(s) == 0 {
 		return 0, nil
 	}
 	if b.r >= len(b.buf) {
-		b.Reset()
+		b.rewind()
 	}
 	b.grow(len(s))
 	b.buf = append(b.buf, s...)
 	return len(s), nil
 }
 
+// WriteStrings appends every part in order, growing at most once for their
+// combined length, and returns the total number of bytes written.
+func (b *Buffer) WriteStrings(parts ...string) (int, error) {
+	n := 0
+	for _, s := range parts {
+		n += len(s)
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if b.exceeds(n) {
+		return 0, ErrBufferFull
+	}
+	if b.r >= len(b.buf) {
+		b.rewind()
+	}
+	b.grow(n)
+	for _, s := range parts {
+		b.buf = append(b.buf, s...)
+	}
+	return n, nil
+}
+
+// AppendBytes is the []byte counterpart of WriteStrings.
+func (b *Buffer) AppendBytes(parts ...[]byte) (int, error) {
+	n := 0
+	for _, p := range parts {
+		n += len(p)
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if b.exceeds(n) {
+		return 0, ErrBufferFull
+	}
+	if b.r >= len(b.buf) {
+		b.rewind()
+	}
+	b.grow(n)
+	for _, p := range parts {
+		b.buf = append(b.buf, p...)
+	}
+	return n, nil
+}
+
+// WriteRepeat appends count copies of v, growing once, and returns the number
+// of bytes written. A non-positive count writes nothing.
+func (b *Buffer) WriteRepeat(v byte, count int) (int, error) {
+	return b.WritePattern([]byte{v}, count)
+}
+
+// WritePattern appends count copies of p, growing once and filling by
+// doubling copies, and returns the number of bytes written. It is the
+// in-place analogue of bytes.Repeat. A non-positive count or empty p writes
+// nothing; it panics if the total length overflows an int.
+func (b *Buffer) WritePattern(p []byte, count int) (int, error) {
+	if count <= 0 || len(p) == 0 {
+		return 0, nil
+	}
+	if count > math.MaxInt/len(p) {
+		panic("gobuff.Buffer: WritePattern length overflow")
+	}
+	n := len(p) * count
+	if b.exceeds(n) {
+		return 0, ErrBufferFull
+	}
+	if b.r >= len(b.buf) {
+		b.rewind()
+	}
+	b.grow(n)
+	start := len(b.buf)
+	b.buf = b.buf[:start+n]
+	dst := b.buf[start:]
+	filled := copy(dst, p)
+	for filled < n {
+		filled += copy(dst[filled:], dst[:filled])
+	}
+	return n, nil
+}
+
+// WriteStringRuneLimited appends at most maxRunes runes of s, never splitting a
+// multi-byte rune, and returns the number of bytes written. Each invalid UTF-8
+// byte counts as one rune and is copied as is.
+func (b *Buffer) WriteStringRuneLimited(s string, maxRunes int) (int, error) {
+	if maxRunes <= 0 {
+		return 0, nil
+	}
+	end := len(s)
+	runes := 0
+	for i := range s {
+		if runes == maxRunes {
+			end = i
+			break
+		}
+		runes++
+	}
+	return b.WriteString(s[:end])
+}
+
+// WriteRune appends the UTF-8 encoding of r and returns its length.
+// Invalid runes are written as utf8.RuneError.
+func (b *Buffer) WriteRune(r rune) (int, error) {
+	if uint32(r) < utf8.RuneSelf {
+		if err := b.WriteByte(byte(r)); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+	size := utf8.RuneLen(r)
+	if size < 0 {
+		size = len(string(utf8.RuneError))
+	}
+	if b.exceeds(size) {
+		return 0, ErrBufferFull
+	}
+	if b.r >= len(b.buf) {
+		b.rewind()
+	}
+	b.grow(size)
+	prev := len(b.buf)
+	b.buf = utf8.AppendRune(b.buf, r)
+	return len(b.buf) - prev, nil
+}
+
+// WithScratch hands fn a zeroed n-byte scratch slice and appends it to the
+// buffer once fn returns. Small requests reuse storage embedded in the Buffer;
+// nested calls from within fn, or n larger than the embedded scratch, fall back
+// to a temporary allocation. The slice must not be retained after fn returns.
+// If appending n bytes would exceed the SetMaxSize limit, nothing is appended
+// and ErrBufferFull is returned; fn still runs.
+func (b *Buffer) WithScratch(n int, fn func(scratch []byte)) error {
+	if n <= 0 {
+		return nil
+	}
+	var s []byte
+	if n <= scratchSize && !b.scratchBusy {
+		b.scratchBusy = true
+		defer func() { b.scratchBusy = false }()
+		s = b.scratch[:n]
+		clear(s)
+	} else {
+		s = make([]byte, n)
+	}
+	fn(s)
+	_, err := b.Write(s)
+	return err
+}
+
+// ConcatFrom appends the unread contents of src to b and resets src.
+// When b has no unread data the backing slices are swapped instead of copied,
+// so src keeps b's old storage for reuse. If the contents would exceed b's
+// SetMaxSize limit, it returns ErrBufferFull and leaves both buffers untouched.
+func (b *Buffer) ConcatFrom(src *Buffer) (int, error) {
+	if src == nil || src == b {
+		return 0, nil
+	}
+	n := src.Len()
+	if n == 0 {
+		src.Reset()
+		return 0, nil
+	}
+	if b.exceeds(n) {
+		return 0, ErrBufferFull
+	}
+	// Arrays only change hands between buffers sharing an Allocator, so each
+	// is still freed by the allocator that made it.
+	if b.r >= len(b.buf) && b.alloc == src.alloc {
+		b.buf, src.buf = src.buf, b.buf
+		b.r, src.r = src.r, 0
+		src.Reset()
+		return n, nil
+	}
+	if _, err := b.Write(src.Bytes()); err != nil {
+		return 0, err
+	}
+	src.Reset()
+	return n, nil
+}
+
+// IndexByteFrom returns the index, relative to the unread region, of the first
+// c at or after offset start, or -1. Parsers can remember how far they scanned
+// and resume from there after more data is written.
+func (b *Buffer) IndexByteFrom(start int, c byte) int {
+	if start < 0 {
+		start = 0
+	}
+	unread := b.Bytes()
+	if start >= len(unread) {
+		return -1
+	}
+	i := bytes.IndexByte(unread[start:], c)
+	if i < 0 {
+		return -1
+	}
+	return start + i
+}
+
+// SearchAny returns the index, relative to the unread region, of the earliest
+// occurrence of any of patterns and the position of that pattern in the
+// slice, or (-1, -1) if none occurs. When several patterns match at the same
+// index the first in the slice wins; an empty pattern matches at 0. It runs
+// one bytes.Index per pattern, each limited to the region before the best
+// match so far, so cost grows with the number of patterns.
+func (b *Buffer) SearchAny(patterns [][]byte) (index int, which int) {
+	unread := b.Bytes()
+	index, which = -1, -1
+	for i, p := range patterns {
+		if index == 0 {
+			break // nothing can match earlier, and ties go to the first pattern
+		}
+		hay := unread
+		if index >= 0 {
+			// Only a match starting before index can win.
+			if end := index - 1 + len(p); end < len(hay) {
+				hay = hay[:end]
+			}
+		}
+		if j := bytes.Index(hay, p); j >= 0 && (index < 0 || j < index) {
+			index, which = j, i
+		}
+	}
+	return index, which
+}
+
+// ReplaceAll replaces every non-overlapping occurrence of old with new in the
+// unread region and returns the number of replacements. Equal-length
+// replacements are done in place; longer replacements grow the buffer once.
+// An empty old matches nothing.
+func (b *Buffer) ReplaceAll(old, new []byte) int {
+	if len(old) == 0 || b.r >= len(b.buf) {
+		return 0
+	}
+	n := bytes.Count(b.buf[b.r:], old)
+	if n == 0 {
+		return 0
+	}
+	if len(old) == len(new) {
+		data := b.buf[b.r:]
+		for i := 0; ; {
+			j := bytes.Index(data[i:], old)
+			if j < 0 {
+				break
+			}
+			copy(data[i+j:], new)
+			i += j + len(old)
+		}
+		return n
+	}
+	unread := len(b.buf) - b.r
+	shift := 0
+	if delta := n * (len(new) - len(old)); delta > 0 {
+		// Move the unread data to the tail of the grown region so the forward
+		// pass below never overwrites bytes it has yet to scan.
+		b.grow(delta)
+		b.buf = b.buf[:len(b.buf)+delta]
+		shift = delta
+		copy(b.buf[b.r+shift:], b.buf[b.r:b.r+unread])
+	}
+	data := b.buf[b.r:]
+	src := data[shift : shift+unread]
+	w, i := 0, 0
+	for {
+		j := bytes.Index(src[i:], old)
+		if j < 0 {
+			break
+		}
+		w += copy(data[w:], src[i:i+j])
+		w += copy(data[w:], new)
+		i += j + len(old)
+	}
+	w += copy(data[w:], src[i:])
+	b.buf = b.buf[:b.r+w]
+	return n
+}
+
+// SortRecords treats the unread region as contiguous recordSize-byte records
+// and stably sorts them in place using less. It returns ErrRecordSize if
+// recordSize is not positive or Len() is not a multiple of it.
+func (b *Buffer) SortRecords(recordSize int, less func(a, b []byte) bool) error {
+	if recordSize <= 0 || b.Len()%recordSize != 0 {
+		return ErrRecordSize
+	}
+	if b.Len() <= recordSize {
+		return nil
+	}
+	sort.Stable(&recordSorter{
+		data: b.buf[b.r:],
+		size: recordSize,
+		less: less,
+		tmp:  make([]byte, recordSize),
+	})
+	return nil
+}
+
+type recordSorter struct {
+	data []byte
+	size int
+	less func(a, b []byte) bool
+	tmp  []byte
+}
+
+func (s *recordSorter) Len() int { return len(s.data) / s.size }
+
+func (s *recordSorter) Less(i, j int) bool { return s.less(s.record(i), s.record(j)) }
+
+func (s *recordSorter) Swap(i, j int) {
+	a, c := s.record(i), s.record(j)
+	copy(s.tmp, a)
+	copy(a, c)
+	copy(c, s.tmp)
+}
+
+func (s *recordSorter) record(i int) []byte {
+	return s.data[i*s.size : (i+1)*s.size : (i+1)*s.size]
+}
+
+// TrailingPartialRune returns how many bytes at the end of the unread region
+// begin a UTF-8 sequence that is not yet complete, or 0 if the buffer ends on
+// a rune boundary. Invalid bytes count as complete, so they are never held back.
+func (b *Buffer) TrailingPartialRune() int {
+	p := b.Bytes()
+	for i := 1; i < utf8.UTFMax && i <= len(p); i++ {
+		c := p[len(p)-i]
+		if c < utf8.RuneSelf {
+			return 0
+		}
+		if utf8.RuneStart(c) {
+			if utf8.FullRune(p[len(p)-i:]) {
+				return 0
+			}
+			return i
+		}
+	}
+	return 0
+}
+
+// TrailingPartial returns how many trailing unread bytes do not make up a
+// whole recordSize-byte record. It returns 0 if recordSize is not positive.
+func (b *Buffer) TrailingPartial(recordSize int) int {
+	if recordSize <= 0 {
+		return 0
+	}
+	return b.Len() % recordSize
+}
+
 // Read copies data from the buffer into p.
 // It returns io.EOF when no data remains.
 func (b *Buffer) Read(p []byte) (int, error) {
+	b.lastRead = 0
 	if len(p) == 0 {
 		return 0, nil
 	}
 	if b.r >= len(b.buf) {
-		b.Reset()
+		b.rewind()
 		return 0, io.EOF
 	}
 	n := copy(p, b.buf[b.r:])
 	b.r += n
 	if b.r >= len(b.buf) {
-		b.Reset()
+		b.rewind()
 	}
 	return n, nil
 }
 
+// ReadByte implements io.ByteReader. It returns io.EOF when no data remains.
+// The consumed byte stays in place until the next write, so UnreadByte can
+// restore it even when it was the last one.
+func (b *Buffer) ReadByte() (byte, error) {
+	if b.r >= len(b.buf) {
+		b.rewind()
+		return 0, io.EOF
+	}
+	c := b.buf[b.r]
+	b.r++
+	b.lastRead = 1
+	return c, nil
+}
+
+// ReadRune implements io.RuneReader, decoding the next UTF-8 rune from the
+// unread region. Invalid encodings yield (utf8.RuneError, 1), as in bytes.Buffer.
+func (b *Buffer) ReadRune() (r rune, size int, err error) {
+	if b.r >= len(b.buf) {
+		b.rewind()
+		return 0, 0, io.EOF
+	}
+	if c := b.buf[b.r]; c < utf8.RuneSelf {
+		b.r++
+		b.lastRead = 1
+		return rune(c), 1, nil
+	}
+	r, size = utf8.DecodeRune(b.buf[b.r:])
+	b.r += size
+	b.lastRead = size
+	return r, size, nil
+}
+
+// UnreadByte implements io.ByteScanner, stepping the cursor back over the last
+// byte consumed by ReadByte, ReadRune, or ReadBytes. Any write, Read, or Reset in between makes it
+// return ErrUnreadByte.
+func (b *Buffer) UnreadByte() error {
+	if b.lastRead == 0 || b.r == 0 {
+		return ErrUnreadByte
+	}
+	b.lastRead = 0
+	b.r--
+	return nil
+}
+
+// ReadBytes reads until the first occurrence of delim, returning a copy of the
+// data up to and including it. If delim is not found it returns the remaining
+// data and io.EOF. The copy stays valid after later writes compact the buffer.
+func (b *Buffer) ReadBytes(delim byte) ([]byte, error) {
+	line, err := b.readSlice(delim)
+	return append([]byte(nil), line...), err
+}
+
+// ReadString is like ReadBytes but returns a string.
+func (b *Buffer) ReadString(delim byte) (string, error) {
+	line, err := b.readSlice(delim)
+	return string(line), err
+}
+
+// readSlice advances past the next delim and returns the consumed bytes,
+// aliasing the buffer.
+func (b *Buffer) readSlice(delim byte) ([]byte, error) {
+	if b.r >= len(b.buf) {
+		b.rewind()
+		return nil, io.EOF
+	}
+	end := len(b.buf)
+	var err error
+	if i := bytes.IndexByte(b.buf[b.r:], delim); i >= 0 {
+		end = b.r + i + 1
+	} else {
+		err = io.EOF
+	}
+	line := b.buf[b.r:end]
+	b.r = end
+	b.lastRead = len(line)
+	return line, err
+}
+
+// Next consumes up to n unread bytes and returns them. The slice aliases the
+// buffer and is only valid until the next mutating call: a following Write
+// may compact the consumed bytes away or reallocate, overwriting it.
+func (b *Buffer) Next(n int) []byte {
+	if n <= 0 {
+		return nil
+	}
+	if m := b.Len(); n > m {
+		n = m
+	}
+	if n == 0 {
+		return nil
+	}
+	p := b.buf[b.r : b.r+n : b.r+n]
+	b.r += n
+	b.lastRead = 0
+	return p
+}
+
+// Peek returns the next n unread bytes without advancing the cursor. If fewer
+// than n are buffered it returns what is available with io.EOF. The slice
+// aliases the buffer and is invalidated by the next Write or growth, unless the
+// buffer is in SetStable mode.
+func (b *Buffer) Peek(n int) ([]byte, error) {
+	if n < 0 {
+		n = 0
+	}
+	var err error
+	if m := b.Len(); n > m {
+		n = m
+		err = io.EOF
+	}
+	return b.buf[b.r : b.r+n : b.r+n], err
+}
+
+// Pull returns up to maxLen unread bytes (all of them if maxLen <= 0) without
+// consuming them, plus an advance func that consumes exactly that chunk. Not
+// calling advance leaves the cursor where it was, so a failed consumer can
+// retry the same chunk. advance is a no-op if the cursor has moved since Pull
+// or it was already called. done is true (and chunk nil) when the buffer is
+// empty. The chunk aliases the buffer.
+func (b *Buffer) Pull(maxLen int) (chunk []byte, advance func(), done bool) {
+	if b.r >= len(b.buf) {
+		return nil, func() {}, true
+	}
+	n := b.Len()
+	if maxLen > 0 && n > maxLen {
+		n = maxLen
+	}
+	start := b.r
+	chunk = b.buf[start : start+n : start+n]
+	advance = func() {
+		if b.r != start || len(b.buf) < start+n {
+			return
+		}
+		b.r += n
+		b.lastRead = 0
+		if b.r >= len(b.buf) {
+			b.rewind()
+		}
+	}
+	return chunk, advance, false
+}
+
+// Discard skips up to n unread bytes without copying them and returns how many
+// were skipped, with io.EOF if fewer than n were available. Like Read, it
+// resets the buffer once the cursor reaches the end.
+func (b *Buffer) Discard(n int) (discarded int, err error) {
+	if n <= 0 {
+		return 0, nil
+	}
+	b.lastRead = 0
+	discarded = n
+	if m := b.Len(); discarded > m {
+		discarded = m
+		err = io.EOF
+	}
+	b.r += discarded
+	if b.r >= len(b.buf) {
+		b.rewind()
+	}
+	return discarded, err
+}
+
+// CopyTo copies up to len(dst) unread bytes into dst, advances the cursor, and
+// returns the count. Like Read, it resets the buffer once it is drained.
+func (b *Buffer) CopyTo(dst []byte) int {
+	b.lastRead = 0
+	if b.r >= len(b.buf) {
+		b.rewind()
+		return 0
+	}
+	n := copy(dst, b.buf[b.r:])
+	b.r += n
+	if b.r >= len(b.buf) {
+		b.rewind()
+	}
+	return n
+}
+
+// Read4 consumes the next 4 bytes and returns them by value, so the result is
+// safe to keep after the buffer is reused. If fewer bytes are available it
+// consumes nothing and returns io.ErrUnexpectedEOF.
+func (b *Buffer) Read4() ([4]byte, error) {
+	var a [4]byte
+	return a, b.readFixed(a[:])
+}
+
+// Read8 is like Read4 for 8 bytes.
+func (b *Buffer) Read8() ([8]byte, error) {
+	var a [8]byte
+	return a, b.readFixed(a[:])
+}
+
+// Read16 is like Read4 for 16 bytes.
+func (b *Buffer) Read16() ([16]byte, error) {
+	var a [16]byte
+	return a, b.readFixed(a[:])
+}
+
+func (b *Buffer) readFixed(p []byte) error {
+	if b.Len() < len(p) {
+		return io.ErrUnexpectedEOF
+	}
+	b.r += copy(p, b.buf[b.r:])
+	b.lastRead = 0
+	return nil
+}
+
+// ShortWriteError reports a writer that accepted fewer bytes than offered
+// without returning an error. It matches io.ErrShortWrite under errors.Is.
+type ShortWriteError struct {
+	Written int // bytes accepted by the writer
+	Total   int // bytes WriteTo attempted to write
+}
+
+func (e *ShortWriteError) Error() string {
+	return "gobuff: short write: " + strconv.Itoa(e.Written) + " of " + strconv.Itoa(e.Total) + " bytes"
+}
+
+// Is reports whether target is io.ErrShortWrite.
+func (e *ShortWriteError) Is(target error) bool {
+	return target == io.ErrShortWrite
+}
+
 // WriteTo implements io.WriterTo.
+// A short write with no error from w is reported as *ShortWriteError.
 func (b *Buffer) WriteTo(w io.Writer) (int64, error) {
+	b.lastRead = 0
 	if b.r >= len(b.buf) {
-		b.Reset()
+		b.rewind()
 		return 0, nil
 	}
 	p := b.Bytes()
@@ -136,35 +1285,178 @@ func (b *Buffer) WriteTo(w io.Writer) (int64, error) {
 	if n > 0 {
 		b.r += n
 		if b.r >= len(b.buf) {
-			b.Reset()
+			b.rewind()
 		}
 	}
 	if err == nil && n != len(p) {
-		return int64(n), io.ErrShortWrite
+		return int64(n), &ShortWriteError{Written: n, Total: len(p)}
 	}
 	return int64(n), err
 }
 
+// defaultMinRead is the smallest free space ReadFrom ensures before each read.
+const defaultMinRead = 512
+
+// maxConsecutiveEmptyReads bounds how many (0, nil) reads ReadFrom tolerates
+// before giving up with io.ErrNoProgress, matching bufio.
+const maxConsecutiveEmptyReads = 100
+
+// FlushAll writes the unread contents of bufs to w in order, advancing each
+// buffer's cursor by what was written. When w is a socket supporting writev
+// (e.g. *net.TCPConn) all regions go out in a single net.Buffers write;
+// otherwise each buffer is flushed with WriteTo. Nil buffers are skipped.
+func FlushAll(w io.Writer, bufs ...*Buffer) (int64, error) {
+	if _, ok := w.(net.Conn); ok {
+		if _, ok := w.(syscall.Conn); ok {
+			return flushVectored(w, bufs)
+		}
+	}
+	var total int64
+	for _, b := range bufs {
+		if b == nil {
+			continue
+		}
+		n, err := b.WriteTo(w)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func flushVectored(w io.Writer, bufs []*Buffer) (int64, error) {
+	vec := make(net.Buffers, 0, len(bufs))
+	for _, b := range bufs {
+		if b != nil && b.Len() > 0 {
+			vec = append(vec, b.Bytes())
+		}
+	}
+	n, err := vec.WriteTo(w)
+	remaining := n
+	for _, b := range bufs {
+		if b == nil || remaining == 0 {
+			continue
+		}
+		adv := int64(b.Len())
+		if adv > remaining {
+			adv = remaining
+		}
+		b.r += int(adv)
+		if b.r >= len(b.buf) {
+			b.rewind()
+		}
+		remaining -= adv
+	}
+	return n, err
+}
+
+// ReadAt implements io.ReaderAt. Offsets index the whole backing slice as
+// returned by UnsafeBytes, including bytes already consumed, and the read
+// cursor is not moved. Note that once every byte has been read the next write
+// rewinds the buffer, and growth may compact consumed bytes, shifting offsets.
+func (b *Buffer) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrNegativeOffset
+	}
+	if off >= int64(len(b.buf)) {
+		return 0, io.EOF
+	}
+	n := copy(p, b.buf[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// WriteAt implements io.WriterAt using the same offsets as ReadAt. It
+// overwrites existing bytes, or extends the buffer, zero-filling any gap. It
+// never compacts consumed bytes, and the read cursor is not moved.
+func (b *Buffer) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrNegativeOffset
+	}
+	end := int(off) + len(p)
+	if end > cap(b.buf) {
+		nb := b.makeBuf(len(b.buf), b.nextCap(end))
+		copy(nb, b.buf)
+		b.freeBuf(b.buf)
+		b.buf = nb
+	}
+	if prev := len(b.buf); end > prev {
+		b.buf = b.buf[:end]
+		if int(off) > prev {
+			clear(b.buf[prev:off])
+		}
+	}
+	b.lastRead = 0
+	return copy(b.buf[off:], p), nil
+}
+
+// WriteAtGrow writes p at offset off within the unread region, growing the
+// buffer as needed and zero-filling any gap between the current end and off.
+// Len afterwards covers the highest byte written, so out-of-order fragments
+// assemble in place. Unlike WriteAt, off is relative to the read cursor.
+func (b *Buffer) WriteAtGrow(p []byte, off int) error {
+	if off < 0 {
+		return ErrNegativeOffset
+	}
+	_, err := b.WriteAt(p, int64(b.r+off))
+	return err
+}
+
 // ReadFrom implements io.ReaderFrom.
+// A reader that keeps returning (0, nil) makes it fail with io.ErrNoProgress.
 func (b *Buffer) ReadFrom(r io.Reader) (int64, error) {
+	return b.ReadFromMin(r, defaultMinRead)
+}
+
+// ReadFromMin behaves like ReadFrom but grows by at least minRead bytes whenever
+// the buffer runs out of space. Larger values reduce the number of Read calls on
+// high-throughput sources. Values <= 0 fall back to the default of 512.
+func (b *Buffer) ReadFromMin(r io.Reader, minRead int) (int64, error) {
 	var total int64
 	if b.r >= len(b.buf) {
-		b.Reset()
+		b.rewind()
+	}
+	if minRead <= 0 {
+		minRead = defaultMinRead
+	}
+	// Sources such as bufio.Reader know how much data is already available;
+	// size for it up front so the common case needs a single allocation.
+	if br, ok := r.(interface{ Buffered() int }); ok {
+		if n := br.Buffered(); n > 0 {
+			b.grow(b.clampToMax(n + minRead))
+		}
 	}
-	const minRead = 512
+	empty := 0
 	for {
+		if b.maxSize > 0 && b.Len() >= b.maxSize {
+			return total, b.probeFull(r)
+		}
 		// Ensure there is space to read into.
 		if len(b.buf) == cap(b.buf) {
-			b.grow(minRead)
+			b.grow(b.clampToMax(minRead))
 		}
 		start := len(b.buf)
-		b.buf = b.buf[:cap(b.buf)]
+		end := cap(b.buf)
+		if b.maxSize > 0 && end-start > b.maxSize-b.Len() {
+			end = start + b.maxSize - b.Len()
+		}
+		b.buf = b.buf[:end]
 		n, err := r.Read(b.buf[start:])
 		if n > 0 {
 			b.buf = b.buf[:start+n]
 			total += int64(n)
+			empty = 0
 		} else {
 			b.buf = b.buf[:start]
+			if err == nil {
+				empty++
+				if empty >= maxConsecutiveEmptyReads {
+					return total, io.ErrNoProgress
+				}
+			}
 		}
 		if err != nil {
 			if err == io.EOF {
@@ -175,20 +1467,61 @@ func (b *Buffer) ReadFrom(r io.Reader) (int64, error) {
 	}
 }
 
-// grow ensures capacity for n additional bytes using power-of-two growth.
+// clampToMax limits a growth request of n bytes to the room left under the
+// SetMaxSize limit.
+func (b *Buffer) clampToMax(n int) int {
+	if b.maxSize > 0 {
+		if room := b.maxSize - b.Len(); n > room {
+			return room
+		}
+	}
+	return n
+}
+
+// probeFull is called by ReadFrom once the buffer holds maxSize bytes. When r
+// is an io.ByteScanner it reads one byte and unreads it to tell a source that
+// is exactly drained (nil) from one with more to give (ErrBufferFull). Other
+// sources are not read, since the probed byte could not be given back, and
+// always report ErrBufferFull.
+func (b *Buffer) probeFull(r io.Reader) error {
+	br, ok := r.(io.ByteScanner)
+	if !ok {
+		return ErrBufferFull
+	}
+	if _, err := br.ReadByte(); err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	if err := br.UnreadByte(); err != nil {
+		return err
+	}
+	return ErrBufferFull
+}
+
+// grow ensures capacity for n additional bytes using the buffer's growth strategy.
 func (b *Buffer) grow(n int) {
 	if n <= 0 {
 		return
 	}
+	b.lastRead = 0
 	if b.r >= len(b.buf) {
-		b.Reset()
+		b.rewind()
 	}
 	// Fast path: enough free capacity at the end.
 	if cap(b.buf)-len(b.buf) >= n {
 		return
 	}
+	if b.resumable {
+		newBuf := b.makeBuf(len(b.buf), b.nextCap(len(b.buf)+n))
+		copy(newBuf, b.buf)
+		b.freeBuf(b.buf)
+		b.buf = newBuf
+		return
+	}
 	// Reclaim space from consumed bytes by compacting.
-	if b.r > 0 {
+	if b.r > 0 && !b.stable {
 		unread := len(b.buf) - b.r
 		if unread+n <= cap(b.buf) {
 			copy(b.buf[:unread], b.buf[b.r:])
@@ -200,13 +1533,50 @@ func (b *Buffer) grow(n int) {
 	// Allocate a new slice sized for unread data + n.
 	unread := len(b.buf) - b.r
 	required := unread + n
-	newCap := nextPowerOfTwo(required)
-	newBuf := make([]byte, unread, newCap)
+	newCap := b.nextCap(required)
+	if b.maxSize > 0 && newCap > b.maxSize && required <= b.maxSize {
+		newCap = b.maxSize
+	}
+	newBuf := b.makeBuf(unread, newCap)
 	copy(newBuf, b.buf[b.r:])
+	b.freeBuf(b.buf)
 	b.buf = newBuf
 	b.r = 0
 }
 
+// nextCap returns the capacity to allocate for at least required bytes.
+func (b *Buffer) nextCap(required int) int {
+	if hint := b.growthHint; hint > 0 {
+		b.growthHint = 0
+		if hint >= required {
+			return hint
+		}
+	}
+	switch b.growth {
+	case GrowExact:
+		return required
+	case GrowFactor1_5:
+		if c := cap(b.buf) + cap(b.buf)/2; c > required {
+			return c
+		}
+		return required
+	case GrowDampened:
+		limit := b.growthLimit
+		if limit <= 0 {
+			limit = DefaultGrowthThreshold
+		}
+		if required <= limit {
+			return nextPowerOfTwo(required)
+		}
+		if c := cap(b.buf) + (cap(b.buf)+3)/4; c > required {
+			return c
+		}
+		return required
+	default:
+		return nextPowerOfTwo(required)
+	}
+}
+
 func nextPowerOfTwo(n int) int {
 	if n <= 0 {
 		return 0
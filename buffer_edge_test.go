@@ -1,8 +1,12 @@
 package gobuff
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"io"
+	"net"
 	"strings"
 	"testing"
 )
@@ -47,9 +51,13 @@ func TestBufferWriteToShortWrite(t *testing.T) {
 
 	var dst bytes.Buffer
 	n, err := b.WriteTo(shortWriter{w: &dst, limit: 2})
-	if err != io.ErrShortWrite {
+	if !errors.Is(err, io.ErrShortWrite) {
 		t.Fatalf("expected ErrShortWrite, got %v", err)
 	}
+	var swe *ShortWriteError
+	if !errors.As(err, &swe) || swe.Written != 2 || swe.Total != 5 {
+		t.Fatalf("expected ShortWriteError{2, 5}, got %#v", err)
+	}
 	if n != 2 {
 		t.Fatalf("expected n=2, got %d", n)
 	}
@@ -71,3 +79,447 @@ func TestBufferReadFromExact(t *testing.T) {
 		t.Fatalf("unexpected contents: %q", b.String())
 	}
 }
+
+func TestBufferReadFromBufferedHint(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 3000)
+	src := bytes.NewReader(data)
+	br := bufio.NewReader(src)
+	b := NewBuffer(0)
+
+	allocs := testing.AllocsPerRun(10, func() {
+		src.Reset(data)
+		br.Reset(src)
+		if _, err := br.Peek(1); err != nil {
+			t.Fatalf("peek: %v", err)
+		}
+		b.buf, b.r = nil, 0
+		if n, err := b.ReadFrom(br); err != nil || n != int64(len(data)) {
+			t.Fatalf("ReadFrom n=%d err=%v", n, err)
+		}
+	})
+	if allocs != 1 {
+		t.Fatalf("expected a single allocation, got %v", allocs)
+	}
+	if !bytes.Equal(b.Bytes(), data) {
+		t.Fatalf("contents mismatch")
+	}
+}
+
+type stuckReader struct{ calls int }
+
+func (s *stuckReader) Read(p []byte) (int, error) {
+	s.calls++
+	return 0, nil
+}
+
+func TestBufferReadFromNoProgress(t *testing.T) {
+	b := NewBuffer(0)
+	src := &stuckReader{}
+	n, err := b.ReadFrom(src)
+	if err != io.ErrNoProgress {
+		t.Fatalf("expected ErrNoProgress, got %v", err)
+	}
+	if n != 0 || src.calls != maxConsecutiveEmptyReads {
+		t.Fatalf("unexpected n=%d calls=%d", n, src.calls)
+	}
+}
+
+func TestFlushAllNetPipe(t *testing.T) {
+	client, server := net.Pipe()
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(server)
+		done <- data
+	}()
+
+	hdr := NewBuffer(0)
+	_, _ = hdr.WriteString("HDR:")
+	body := NewBuffer(0)
+	_, _ = body.WriteString("xbody")
+	_, _ = body.Read(make([]byte, 1))
+
+	n, err := FlushAll(client, hdr, nil, body)
+	_ = client.Close()
+	if err != nil || n != 8 {
+		t.Fatalf("FlushAll n=%d err=%v", n, err)
+	}
+	if got := string(<-done); got != "HDR:body" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+	if hdr.Len() != 0 || body.Len() != 0 {
+		t.Fatalf("expected cursors at end: hdr=%d body=%d", hdr.Len(), body.Len())
+	}
+}
+
+func TestFlushAllTCPVectored(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("loopback unavailable: %v", err)
+	}
+	defer ln.Close()
+	done := make(chan []byte)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			done <- nil
+			return
+		}
+		data, _ := io.ReadAll(c)
+		_ = c.Close()
+		done <- data
+	}()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	hdr := NewBuffer(0)
+	_, _ = hdr.WriteString("HDR:")
+	body := NewBuffer(0)
+	_, _ = body.WriteString("payload")
+	n, err := FlushAll(conn, hdr, body)
+	_ = conn.Close()
+	if err != nil || n != 11 {
+		t.Fatalf("FlushAll n=%d err=%v", n, err)
+	}
+	if got := string(<-done); got != "HDR:payload" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+	if hdr.Len() != 0 || body.Len() != 0 {
+		t.Fatalf("expected cursors at end: hdr=%d body=%d", hdr.Len(), body.Len())
+	}
+}
+
+func TestFlushAllShortWrite(t *testing.T) {
+	a := NewBuffer(0)
+	_, _ = a.WriteString("abc")
+	c := NewBuffer(0)
+	_, _ = c.WriteString("def")
+
+	var dst bytes.Buffer
+	n, err := FlushAll(shortWriter{w: &dst, limit: 2}, a, c)
+	if !errors.Is(err, io.ErrShortWrite) || n != 2 {
+		t.Fatalf("expected short write after 2 bytes, n=%d err=%v", n, err)
+	}
+	if a.String() != "c" || c.String() != "def" {
+		t.Fatalf("unexpected cursors: a=%q c=%q", a.String(), c.String())
+	}
+}
+
+// readerOnly and writerOnly hide any WriterTo/ReaderFrom methods so io.Copy
+// can only shortcut through the Buffer side.
+type readerOnly struct{ r io.Reader }
+
+func (r readerOnly) Read(p []byte) (int, error) { return r.r.Read(p) }
+
+type writerOnly struct{ w io.Writer }
+
+func (w writerOnly) Write(p []byte) (int, error) { return w.w.Write(p) }
+
+func TestBufferIOCopyUsesWriteTo(t *testing.T) {
+	payload := bytes.Repeat([]byte("w"), 4096)
+	b := NewBuffer(len(payload))
+	dst := &writerOnly{w: io.Discard}
+	allocs := testing.AllocsPerRun(20, func() {
+		_, _ = b.Write(payload)
+		if n, err := io.Copy(dst, b); err != nil || n != int64(len(payload)) {
+			t.Fatalf("io.Copy n=%d err=%v", n, err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected io.Copy to use WriteTo without allocating, got %v allocs", allocs)
+	}
+}
+
+func TestBufferIOCopyUsesReadFrom(t *testing.T) {
+	payload := bytes.Repeat([]byte("r"), 4096)
+	src := bytes.NewReader(payload)
+	b := NewBuffer(2 * len(payload))
+	wrapped := &readerOnly{r: src}
+	allocs := testing.AllocsPerRun(20, func() {
+		b.Reset()
+		src.Reset(payload)
+		if n, err := io.Copy(b, wrapped); err != nil || n != int64(len(payload)) {
+			t.Fatalf("io.Copy n=%d err=%v", n, err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected io.Copy to use ReadFrom without allocating, got %v allocs", allocs)
+	}
+}
+
+func TestBufferLargeWriteFastPath(t *testing.T) {
+	b := NewBuffer(8)
+	_, _ = b.WriteString("abc")
+	_, _ = b.ReadByte()
+	payload := bytes.Repeat([]byte("z"), 1100)
+	if n, err := b.Write(payload); err != nil || n != len(payload) {
+		t.Fatalf("Write n=%d err=%v", n, err)
+	}
+	if b.Len() != 2+len(payload) || !bytes.Equal(b.Bytes()[:2], []byte("bc")) || !bytes.Equal(b.Bytes()[2:], payload) {
+		t.Fatalf("unexpected contents after large write")
+	}
+	if b.Cap() >= 2048 {
+		t.Fatalf("large write doubled capacity to %d", b.Cap())
+	}
+}
+
+func TestBufferLargeWriteKeepsGrowthStrategy(t *testing.T) {
+	b := NewBuffer(8)
+	b.SetGrowthStrategy(GrowExact)
+	payload := bytes.Repeat([]byte("z"), 1100)
+	_, _ = b.Write(payload)
+	if b.Cap() != len(payload) {
+		t.Fatalf("GrowExact large write: cap = %d, want %d", b.Cap(), len(payload))
+	}
+}
+
+func TestBufferResumableDrain(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789"), 10)
+	b := NewBuffer(16)
+	b.SetResumable(true)
+	_, _ = b.Write(payload)
+
+	// A transfer that fails partway through.
+	var sent bytes.Buffer
+	_, err := b.WriteTo(shortWriter{w: &sent, limit: 40})
+	if err == nil {
+		t.Fatalf("expected a short write error")
+	}
+	checkpoint := b.DrainOffset()
+	if checkpoint != 40 {
+		t.Fatalf("expected checkpoint 40, got %d", checkpoint)
+	}
+
+	// Draining further (e.g. a retry that was lost) and writing more keeps the
+	// consumed bytes, so the checkpoint is still reachable.
+	_, _ = b.WriteTo(io.Discard)
+	_, _ = b.Write(make([]byte, 64))
+	b.Truncate(0)
+	if err := b.SeekDrain(checkpoint); err != nil {
+		t.Fatalf("SeekDrain: %v", err)
+	}
+	if _, err := b.WriteTo(&sent); err != nil {
+		t.Fatalf("resume WriteTo: %v", err)
+	}
+	if !bytes.Equal(sent.Bytes(), payload) {
+		t.Fatalf("resumed transfer mismatch: got %d bytes", sent.Len())
+	}
+
+	// After a restart the buffer is rebuilt from the original contents.
+	fresh := NewBuffer(0)
+	_, _ = fresh.Write(payload)
+	if err := fresh.SeekDrain(checkpoint); err != nil {
+		t.Fatalf("SeekDrain on refilled buffer: %v", err)
+	}
+	if !fresh.EqualBytes(payload[checkpoint:]) {
+		t.Fatalf("expected to resume from the checkpoint")
+	}
+	if err := fresh.SeekDrain(int64(len(payload) + 1)); !errors.Is(err, ErrDrainOffset) {
+		t.Fatalf("expected ErrDrainOffset, got %v", err)
+	}
+	if err := fresh.SeekDrain(-1); !errors.Is(err, ErrNegativeOffset) {
+		t.Fatalf("expected ErrNegativeOffset, got %v", err)
+	}
+}
+
+func TestBufferSeek(t *testing.T) {
+	var _ io.ReadSeeker = (*Buffer)(nil)
+
+	b := NewBuffer(0)
+	_, _ = b.WriteString("hello, world")
+
+	size, err := b.Seek(0, io.SeekEnd)
+	if err != nil || size != 12 {
+		t.Fatalf("Seek(0, End) = %d, %v", size, err)
+	}
+	if _, err := b.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Seek(0, Start): %v", err)
+	}
+	all, err := io.ReadAll(b)
+	if err != nil || string(all) != "hello, world" {
+		t.Fatalf("ReadAll = %q, %v", all, err)
+	}
+
+	// Data read to the end is still there to seek back to.
+	pos, err := b.Seek(-5, io.SeekCurrent)
+	if err != nil || pos != 7 {
+		t.Fatalf("Seek(-5, Current) = %d, %v", pos, err)
+	}
+	if got := b.String(); got != "world" {
+		t.Fatalf("expected %q after seeking back, got %q", "world", got)
+	}
+
+	if pos, _ := b.Seek(100, io.SeekStart); pos != 12 {
+		t.Fatalf("expected seek past end to clamp to 12, got %d", pos)
+	}
+	if _, err := b.Seek(-13, io.SeekEnd); !errors.Is(err, ErrNegativeOffset) {
+		t.Fatalf("expected ErrNegativeOffset, got %v", err)
+	}
+	if _, err := b.Seek(0, 42); !errors.Is(err, ErrInvalidWhence) {
+		t.Fatalf("expected ErrInvalidWhence, got %v", err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("failed seeks must not move the cursor, len=%d", b.Len())
+	}
+
+	p := NewBufferPoolWithOptions(PoolOptions{BoundedFreelists: true})
+	pb := p.Get()
+	_, _ = pb.Seek(0, io.SeekStart)
+	p.Put(pb)
+	if again := p.Get(); again.resumable {
+		t.Fatalf("expected Put to clear the retain mode enabled by Seek")
+	}
+}
+
+func TestBufferStableViews(t *testing.T) {
+	b := NewBuffer(8)
+	b.SetStable(true)
+	_, _ = b.WriteString("abcdefgh")
+	_ = b.Next(4)
+	view, _ := b.Peek(4)
+
+	// Without stable mode this write would compact "efgh" to the front,
+	// overwriting the bytes view points at.
+	_, _ = b.WriteString("ij")
+	if string(view) != "efgh" {
+		t.Fatalf("stable view changed: %q", view)
+	}
+	if got := b.String(); got != "efghij" {
+		t.Fatalf("unexpected contents: %q", got)
+	}
+
+	c := NewBuffer(8)
+	_, _ = c.WriteString("abcdefgh")
+	_ = c.Next(4)
+	moved, _ := c.Peek(4)
+	_, _ = c.WriteString("ijkl")
+	if string(moved) == "efgh" || c.Cap() != 8 {
+		t.Fatalf("expected default mode to compact in place (view %q, cap %d)", moved, c.Cap())
+	}
+}
+
+func TestBufferMaxSizeWrites(t *testing.T) {
+	b := NewBuffer(0)
+	b.SetMaxSize(8)
+	if _, err := b.WriteString("12345"); err != nil {
+		t.Fatalf("write within limit: %v", err)
+	}
+	if n, err := b.Write([]byte("6789")); n != 0 || !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("expected ErrBufferFull writing nothing, got %d, %v", n, err)
+	}
+	if _, err := b.WriteRune('€'); err != nil {
+		t.Fatalf("rune within limit: %v", err)
+	}
+	if err := b.WriteByte('x'); !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("expected ErrBufferFull, got %v", err)
+	}
+	if b.Cap() > 8 {
+		t.Fatalf("expected growth capped at 8, got cap %d", b.Cap())
+	}
+
+	// Consuming data frees room under the limit.
+	_ = b.Next(4)
+	if _, err := b.WriteStrings("ab", "cd"); err != nil {
+		t.Fatalf("write after consuming: %v", err)
+	}
+}
+
+func TestBufferMaxSizeConcatAndScratch(t *testing.T) {
+	dst := NewBuffer(0)
+	dst.SetMaxSize(2)
+	_, _ = dst.WriteString("ab")
+	_ = dst.Next(1)
+	src := NewBuffer(0)
+	_, _ = src.WriteString("cdefgh")
+	if n, err := dst.ConcatFrom(src); n != 0 || !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("ConcatFrom = %d, %v, want 0 and ErrBufferFull", n, err)
+	}
+	if dst.String() != "b" || src.String() != "cdefgh" {
+		t.Fatalf("failed ConcatFrom changed buffers: dst=%q src=%q", dst.String(), src.String())
+	}
+
+	empty := NewBuffer(0)
+	empty.SetMaxSize(2)
+	if _, err := empty.ConcatFrom(src); !errors.Is(err, ErrBufferFull) || empty.Len() != 0 || src.Len() != 6 {
+		t.Fatalf("swap path ignored the limit: err=%v dst=%d src=%d", err, empty.Len(), src.Len())
+	}
+
+	if err := empty.WithScratch(3, func(s []byte) { copy(s, "xyz") }); !errors.Is(err, ErrBufferFull) || empty.Len() != 0 {
+		t.Fatalf("WithScratch = %v with len %d, want ErrBufferFull and nothing written", err, empty.Len())
+	}
+}
+
+func TestBufferMaxSizeBinaryWrites(t *testing.T) {
+	b := NewBuffer(0)
+	b.SetMaxSize(6)
+	if _, err := b.WriteLengthPrefixedString("hello", PrefixUint32BE); !errors.Is(err, ErrBufferFull) || b.Len() != 0 {
+		t.Fatalf("expected no partial frame, got err=%v len=%d", err, b.Len())
+	}
+	if n, err := b.WriteLengthPrefixedString("hello", PrefixUvarint); err != nil || n != 6 {
+		t.Fatalf("frame within limit: n=%d err=%v", n, err)
+	}
+	if err := b.WriteUint16(binary.BigEndian, 1); !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("WriteUint16: %v", err)
+	}
+	if err := b.WriteUint32(binary.BigEndian, 1); !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("WriteUint32: %v", err)
+	}
+	if err := b.WriteUint64(binary.BigEndian, 1); !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("WriteUint64: %v", err)
+	}
+	if _, err := b.WriteUvarint(1); !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("WriteUvarint: %v", err)
+	}
+	if _, err := b.WriteVarint(-1); !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("WriteVarint: %v", err)
+	}
+	if b.Len() != 6 {
+		t.Fatalf("rejected writes changed the buffer: len=%d", b.Len())
+	}
+}
+
+func TestBufferMaxSizeReadFrom(t *testing.T) {
+	payload := bytes.Repeat([]byte("z"), 10000)
+
+	b := NewBuffer(0)
+	b.SetMaxSize(4096)
+	n, err := b.ReadFrom(bytes.NewReader(payload))
+	if !errors.Is(err, ErrBufferFull) || n != 4096 || b.Len() != 4096 {
+		t.Fatalf("expected 4096 bytes then ErrBufferFull, got n=%d len=%d err=%v", n, b.Len(), err)
+	}
+	if b.Cap() > 4096 {
+		t.Fatalf("expected ReadFrom not to allocate past the limit, cap=%d", b.Cap())
+	}
+
+	exact := NewBuffer(0)
+	exact.SetMaxSize(len(payload))
+	if n, err := exact.ReadFrom(bytes.NewReader(payload)); err != nil || n != int64(len(payload)) {
+		t.Fatalf("source that exactly fits should succeed, got n=%d err=%v", n, err)
+	}
+
+	for _, src := range []io.Reader{
+		strings.NewReader("abcdefgh"),
+		bufio.NewReader(strings.NewReader("abcdefgh")),
+		io.MultiReader(strings.NewReader("abcdefgh")), // no UnreadByte
+	} {
+		small := NewBuffer(0)
+		small.SetMaxSize(4)
+		if _, err := small.ReadFrom(src); !errors.Is(err, ErrBufferFull) || small.String() != "abcd" {
+			t.Fatalf("%T: got %q, %v", src, small.String(), err)
+		}
+		if rest, _ := io.ReadAll(src); string(rest) != "efgh" {
+			t.Fatalf("%T: ReadFrom consumed past the limit, reader left %q", src, rest)
+		}
+	}
+
+	src := &countingReader{data: payload}
+	limited := NewBuffer(0)
+	limited.SetMaxSize(1000)
+	if _, err := limited.ReadFromMin(src, 4096); !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("expected ErrBufferFull from ReadFromMin, got %v", err)
+	}
+	if src.off > 1001 {
+		t.Fatalf("expected reads to stop near the limit, source advanced %d bytes", src.off)
+	}
+}
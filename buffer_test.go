@@ -2,9 +2,15 @@ package gobuff
 
 import (
 	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestBufferReserveAndLen(t *testing.T) {
@@ -62,3 +68,1119 @@ func TestBufferReadEOF(t *testing.T) {
 		t.Fatalf("expected EOF, got %v", err)
 	}
 }
+
+func TestBufferReplaceAll(t *testing.T) {
+	cases := []struct {
+		name, in, old, new, want string
+		count                    int
+	}{
+		{"same length", "a-b-c", "-", "+", "a+b+c", 2},
+		{"longer", "a-b-c", "-", "<->", "a<->b<->c", 2},
+		{"shorter", "a--b--c--", "--", "", "abc", 3},
+		{"no match", "abc", "x", "yy", "abc", 0},
+		{"overlapping", "aaaa", "aa", "b", "bb", 2},
+	}
+	for _, tc := range cases {
+		b := NewBuffer(0)
+		_, _ = b.WriteString(tc.in)
+		if got := b.ReplaceAll([]byte(tc.old), []byte(tc.new)); got != tc.count {
+			t.Fatalf("%s: expected %d replacements, got %d", tc.name, tc.count, got)
+		}
+		if got := b.String(); got != tc.want {
+			t.Fatalf("%s: unexpected contents: %q", tc.name, got)
+		}
+	}
+}
+
+func TestBufferReplaceAllUnreadOnly(t *testing.T) {
+	b := NewBuffer(0)
+	_, _ = b.WriteString("x.y.z")
+	tmp := make([]byte, 2)
+	_, _ = b.Read(tmp)
+	if n := b.ReplaceAll([]byte("."), []byte("::")); n != 1 {
+		t.Fatalf("expected 1 replacement, got %d", n)
+	}
+	if got := b.String(); got != "y::z" {
+		t.Fatalf("unexpected contents: %q", got)
+	}
+}
+
+func TestBufferMarshalBinary(t *testing.T) {
+	var _ encoding.BinaryMarshaler = (*Buffer)(nil)
+	var _ encoding.BinaryUnmarshaler = (*Buffer)(nil)
+
+	b := NewBuffer(0)
+	_, _ = b.WriteString("xpayload")
+	_, _ = b.ReadByte()
+	data, err := b.MarshalBinary()
+	if err != nil || string(data) != "payload" {
+		t.Fatalf("MarshalBinary = %q, %v", data, err)
+	}
+	b.Reset()
+	_, _ = b.WriteString("REUSED!")
+	if string(data) != "payload" {
+		t.Fatalf("marshaled bytes alias the buffer: %q", data)
+	}
+
+	type envelope struct {
+		Name    string
+		Payload *Buffer
+	}
+	var wire bytes.Buffer
+	if err := gob.NewEncoder(&wire).Encode(envelope{Name: "n", Payload: b}); err != nil {
+		t.Fatalf("gob encode: %v", err)
+	}
+	var out envelope
+	if err := gob.NewDecoder(&wire).Decode(&out); err != nil {
+		t.Fatalf("gob decode: %v", err)
+	}
+	if out.Name != "n" || out.Payload.String() != "REUSED!" {
+		t.Fatalf("unexpected round trip: %q %q", out.Name, out.Payload.String())
+	}
+
+	if err := out.Payload.UnmarshalBinary([]byte("new")); err != nil || out.Payload.String() != "new" {
+		t.Fatalf("UnmarshalBinary should replace contents, got %q", out.Payload.String())
+	}
+}
+
+func TestBufferJSON(t *testing.T) {
+	type doc struct {
+		ID      int     `json:"id"`
+		Payload *Buffer `json:"payload"`
+	}
+	b := NewBuffer(0)
+	_, _ = b.Write([]byte{0x00, 0xff, 'h', 'i'})
+	out, err := json.Marshal(doc{ID: 7, Payload: b})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if want := `{"id":7,"payload":"AP9oaQ=="}`; string(out) != want {
+		t.Fatalf("Marshal = %s, want %s", out, want)
+	}
+
+	dst := NewBuffer(64)
+	_, _ = dst.WriteString("old contents")
+	storage := dst.UnsafeBytes()[:1]
+	in := doc{Payload: dst}
+	if err := json.Unmarshal(out, &in); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !in.Payload.EqualBytes([]byte{0x00, 0xff, 'h', 'i'}) {
+		t.Fatalf("unexpected decoded payload %q", in.Payload.Bytes())
+	}
+	if &storage[0] != &in.Payload.UnsafeBytes()[:1][0] {
+		t.Fatalf("expected decode to reuse the buffer's storage")
+	}
+
+	if err := dst.UnmarshalJSON([]byte(`"AP9\/aQ=="`)); err != nil {
+		t.Fatalf("escaped input: %v", err)
+	}
+	if err := dst.UnmarshalJSON([]byte(`"not base64!"`)); err == nil || dst.Len() != 0 {
+		t.Fatalf("expected decode error and empty buffer, got %v len=%d", err, dst.Len())
+	}
+	if err := dst.UnmarshalJSON([]byte(`123`)); err != ErrJSONNotString {
+		t.Fatalf("expected error for non-string JSON")
+	}
+}
+
+func TestBufferGoString(t *testing.T) {
+	b := NewBuffer(8)
+	_, _ = b.WriteString("xhi\n")
+	_, _ = b.ReadByte()
+	want := "gobuff.Buffer{len:3 cap:8 r:1 head:68 69 0a |hi.|}"
+	if got := fmt.Sprintf("%#v", b); got != want {
+		t.Fatalf("%%#v = %q, want %q", got, want)
+	}
+
+	big := NewBuffer(0)
+	_, _ = big.Write(bytes.Repeat([]byte("a"), 1<<20))
+	got := big.GoString()
+	if len(got) > 120 || !strings.HasSuffix(got, "|aaaaaaaaaaaaaaaa|...}") {
+		t.Fatalf("expected a short truncated summary, got %q", got)
+	}
+	if big.String() != strings.Repeat("a", 1<<20) {
+		t.Fatalf("String must still return the full contents")
+	}
+}
+
+func TestBufferEqualBytes(t *testing.T) {
+	b := NewBuffer(0)
+	_, _ = b.WriteString("xhello")
+	_, _ = b.Read(make([]byte, 1))
+	if !b.EqualBytes([]byte("hello")) {
+		t.Fatalf("expected match for unread region")
+	}
+	if b.EqualBytes([]byte("hellO")) {
+		t.Fatalf("expected mismatch for differing byte")
+	}
+	if b.EqualBytes([]byte("hell")) || b.EqualBytes([]byte("hello!")) {
+		t.Fatalf("expected mismatch for differing lengths")
+	}
+	if b.Len() != 5 {
+		t.Fatalf("EqualBytes moved the cursor: len=%d", b.Len())
+	}
+}
+
+func TestBufferEqualCompare(t *testing.T) {
+	b := NewBuffer(8)
+	_, _ = b.WriteString("xxbeta")
+	_ = b.Next(2)
+	view := b.UnsafeBytes()
+
+	if !b.Equal([]byte("beta")) || b.Equal([]byte("bet")) || b.Equal([]byte("xxbeta")) {
+		t.Fatalf("Equal should compare the unread bytes only")
+	}
+	for _, tc := range []struct {
+		other string
+		want  int
+	}{{"beta", 0}, {"alpha", 1}, {"gamma", -1}, {"bet", 1}, {"betas", -1}} {
+		if got := b.Compare([]byte(tc.other)); got != tc.want {
+			t.Fatalf("Compare(%q) = %d, want %d", tc.other, got, tc.want)
+		}
+	}
+	if b.Len() != 4 || string(b.UnsafeBytes()) != string(view) {
+		t.Fatalf("Equal/Compare changed the buffer")
+	}
+	if allocs := testing.AllocsPerRun(100, func() { _ = b.Compare([]byte("beta")) }); allocs != 0 {
+		t.Fatalf("expected Compare not to allocate, got %v", allocs)
+	}
+}
+
+func TestBufferEqualIgnoringSuffix(t *testing.T) {
+	nl := []byte("\n")
+	cases := []struct {
+		buf, p string
+		want   bool
+	}{
+		{"line\n", "line", true},
+		{"line", "line\n", true},
+		{"line\n", "line\n", true},
+		{"line", "line", true},
+		{"line\n\n", "line", false},
+		{"line\n", "lime", false},
+	}
+	for _, tc := range cases {
+		b := NewBuffer(0)
+		_, _ = b.WriteString(tc.buf)
+		if got := b.EqualIgnoringSuffix([]byte(tc.p), nl); got != tc.want {
+			t.Fatalf("EqualIgnoringSuffix(%q, %q) = %v, want %v", tc.buf, tc.p, got, tc.want)
+		}
+		if b.String() != tc.buf {
+			t.Fatalf("EqualIgnoringSuffix mutated the buffer: %q", b.String())
+		}
+	}
+}
+
+func TestBufferConcatFrom(t *testing.T) {
+	main := NewBuffer(0)
+	_, _ = main.WriteString("head:")
+	staging := NewBuffer(0)
+	_, _ = staging.WriteString("body")
+
+	n, err := main.ConcatFrom(staging)
+	if err != nil || n != 4 {
+		t.Fatalf("ConcatFrom n=%d err=%v", n, err)
+	}
+	if got := main.String(); got != "head:body" {
+		t.Fatalf("unexpected contents: %q", got)
+	}
+	if staging.Len() != 0 {
+		t.Fatalf("expected staging to be emptied, len=%d", staging.Len())
+	}
+}
+
+func TestBufferConcatFromEmptySwaps(t *testing.T) {
+	main := NewBuffer(0)
+	staging := NewBuffer(16)
+	_, _ = staging.WriteString("xdata")
+	_, _ = staging.Read(make([]byte, 1))
+	backing := &staging.UnsafeBytes()[:1][0]
+
+	if n, _ := main.ConcatFrom(staging); n != 4 {
+		t.Fatalf("expected n=4, got %d", n)
+	}
+	if got := main.String(); got != "data" {
+		t.Fatalf("unexpected contents: %q", got)
+	}
+	if &main.UnsafeBytes()[:1][0] != backing {
+		t.Fatalf("expected backing slice to be moved, not copied")
+	}
+	if staging.Len() != 0 {
+		t.Fatalf("expected staging to be emptied, len=%d", staging.Len())
+	}
+}
+
+func TestBufferWithScratch(t *testing.T) {
+	b := NewBuffer(0)
+	_, _ = b.WriteString("hdr")
+	// A tiny record: 1-byte tag, 2-byte big-endian length, nested 1-byte flag.
+	b.WithScratch(3, func(s []byte) {
+		s[0] = 0x7f
+		s[1], s[2] = 0x01, 0x02
+		b.WithScratch(1, func(inner []byte) {
+			inner[0] = 0xaa
+		})
+	})
+	want := []byte{'h', 'd', 'r', 0xaa, 0x7f, 0x01, 0x02}
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Fatalf("unexpected contents: %v", b.Bytes())
+	}
+
+	b.WithScratch(2, func(s []byte) {
+		if s[0] != 0 || s[1] != 0 {
+			t.Fatalf("expected zeroed scratch, got %v", s)
+		}
+	})
+}
+
+func TestBufferSortRecords(t *testing.T) {
+	b := NewBuffer(0)
+	_, _ = b.WriteString("3a1b2c1d")
+	err := b.SortRecords(2, func(x, y []byte) bool { return x[0] < y[0] })
+	if err != nil {
+		t.Fatalf("SortRecords: %v", err)
+	}
+	if got := b.String(); got != "1b1d2c3a" {
+		t.Fatalf("unexpected order: %q", got)
+	}
+
+	_, _ = b.WriteString("x")
+	if err := b.SortRecords(2, func(x, y []byte) bool { return x[0] < y[0] }); err != ErrRecordSize {
+		t.Fatalf("expected ErrRecordSize, got %v", err)
+	}
+}
+
+func TestBufferMarkField(t *testing.T) {
+	b := NewBuffer(0)
+	b.MarkField("ignored")
+	if b.FieldOffsets() != nil {
+		t.Fatalf("expected no offsets while tracking is off")
+	}
+
+	b.TrackFields(true)
+	b.MarkField("magic")
+	_, _ = b.WriteString("GBUF")
+	b.MarkField("length")
+	_ = b.WriteByte(3)
+	b.MarkField("payload")
+	_, _ = b.WriteString("abc")
+
+	got := b.FieldOffsets()
+	want := map[string]int{"magic": 0, "length": 4, "payload": 5}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected offsets: %v", got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("field %q: expected offset %d, got %d", k, v, got[k])
+		}
+	}
+
+	b.Reset()
+	if len(b.FieldOffsets()) != 0 {
+		t.Fatalf("expected Reset to clear offsets")
+	}
+}
+
+func TestBufferStringUnsafeAliases(t *testing.T) {
+	b := NewBuffer(8)
+	_, _ = b.WriteString("hello")
+	s := b.StringUnsafe()
+	if s != "hello" {
+		t.Fatalf("unexpected string: %q", s)
+	}
+	// Documented caveat: the string shares memory with the buffer.
+	b.Bytes()[0] = 'j'
+	if s != "jello" {
+		t.Fatalf("expected string to alias buffer memory, got %q", s)
+	}
+	if NewBuffer(0).StringUnsafe() != "" {
+		t.Fatalf("expected empty string for empty buffer")
+	}
+}
+
+func TestNewBufferPattern(t *testing.T) {
+	b := NewBufferPattern([]byte("abc"), 11)
+	if b.Len() != 11 || b.String() != "abcabcabcab" {
+		t.Fatalf("unexpected tiling: len=%d %q", b.Len(), b.String())
+	}
+	big := NewBufferPattern([]byte{1, 2, 3, 4, 5, 6, 7}, 1000)
+	for i, c := range big.Bytes() {
+		if c != byte(i%7+1) {
+			t.Fatalf("byte %d = %d, want %d", i, c, i%7+1)
+		}
+	}
+	if NewBufferPattern(nil, 10).Len() != 0 || NewBufferPattern([]byte("x"), 0).Len() != 0 {
+		t.Fatalf("expected empty buffers for empty pattern or length")
+	}
+	if s := NewBufferPattern([]byte("abcdef"), 4).String(); s != "abcd" {
+		t.Fatalf("expected pattern longer than totalLen to be cut, got %q", s)
+	}
+}
+
+func TestAppendBufferAccumulates(t *testing.T) {
+	b := NewAppendBuffer(8)
+	var want []byte
+	for i := 0; i < 1000; i++ {
+		switch i % 3 {
+		case 0:
+			_, _ = b.Write([]byte("ab"))
+			want = append(want, "ab"...)
+		case 1:
+			_ = b.WriteByte('c')
+			want = append(want, 'c')
+		default:
+			_, _ = b.WriteString("def")
+			want = append(want, "def"...)
+		}
+	}
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Fatalf("append buffer lost data: got %d bytes, want %d", b.Len(), len(want))
+	}
+	b.Reset()
+	if b.Len() != 0 {
+		t.Fatalf("expected explicit Reset to empty the buffer")
+	}
+	_, _ = b.WriteString("again")
+	if b.String() != "again" {
+		t.Fatalf("unexpected contents after Reset: %q", b.String())
+	}
+}
+
+func TestBufferReserveAligned(t *testing.T) {
+	for _, align := range []int{16, 32} {
+		b := NewBuffer(0)
+		_, _ = b.WriteString("abc")
+		for i := 0; i < 4; i++ {
+			prev := len(b.UnsafeBytes())
+			s := b.ReserveAligned(20, align)
+			if len(s) != 20 {
+				t.Fatalf("expected 20-byte reservation, got %d", len(s))
+			}
+			start := cap(b.UnsafeBytes()) - cap(s)
+			if start%align != 0 {
+				t.Fatalf("align %d: reservation starts at offset %d", align, start)
+			}
+			if start-prev >= align || !bytes.Equal(b.UnsafeBytes()[prev:start], make([]byte, start-prev)) {
+				t.Fatalf("expected at most align-1 zero padding bytes between %d and %d", prev, start)
+			}
+			copy(s, "payload")
+			_ = b.WriteByte('x') // misalign the next reservation
+		}
+		if !bytes.HasPrefix(b.Bytes(), []byte("abc\x00")) {
+			t.Fatalf("padding should follow existing data: %q", b.Bytes()[:8])
+		}
+	}
+	if s := NewBuffer(0).ReserveAligned(4, 0); len(s) != 4 {
+		t.Fatalf("expected align <= 1 to behave like Reserve")
+	}
+}
+
+func TestBufferResetAndFree(t *testing.T) {
+	b := NewBuffer(0)
+	_, _ = b.Write(make([]byte, 1<<20))
+	_ = b.Next(10)
+	b.ResetAndFree()
+	if b.Len() != 0 || b.Cap() != 0 || b.UnsafeBytes() != nil {
+		t.Fatalf("expected backing array to be dropped, len=%d cap=%d", b.Len(), b.Cap())
+	}
+	_, _ = b.WriteString("small")
+	if b.String() != "small" || b.Cap() >= 1<<20 {
+		t.Fatalf("expected a fresh small allocation, got %q cap=%d", b.String(), b.Cap())
+	}
+}
+
+func TestBufferAvailableBuffer(t *testing.T) {
+	b := NewBuffer(32)
+	_, _ = b.WriteString("n=")
+	if b.Available() != 30 {
+		t.Fatalf("expected 30 bytes available, got %d", b.Available())
+	}
+	allocs := testing.AllocsPerRun(10, func() {
+		b.Truncate(2)
+		if _, err := b.CommitAvailable(strconv.AppendInt(b.AvailableBuffer(), -12345, 10)); err != nil {
+			t.Fatalf("CommitAvailable: %v", err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected in-place commit not to allocate, got %v", allocs)
+	}
+	if b.String() != "n=-12345" {
+		t.Fatalf("unexpected contents %q", b.String())
+	}
+
+	// An append that outgrows the spare capacity reallocates; the commit copies.
+	small := NewBuffer(2)
+	_, _ = small.WriteString("ab")
+	grown := append(small.AvailableBuffer(), "cdef"...)
+	if _, err := small.CommitAvailable(grown); err != nil || small.String() != "abcdef" {
+		t.Fatalf("expected copied commit, got %q, %v", small.String(), err)
+	}
+}
+
+func TestBufferWillReallocate(t *testing.T) {
+	b := NewBuffer(16)
+	_, _ = b.Write(make([]byte, 10))
+	if b.WillReallocate(6) {
+		t.Fatalf("expected a write into free tail space not to reallocate")
+	}
+
+	_, _ = b.Read(make([]byte, 8)) // 8 consumed, 2 unread
+	if b.WillReallocate(12) {
+		t.Fatalf("expected a write that fits after compaction not to reallocate")
+	}
+	if !b.WillReallocate(15) {
+		t.Fatalf("expected a write beyond total capacity to reallocate")
+	}
+	if b.Len() != 2 || b.Cap() != 16 {
+		t.Fatalf("WillReallocate changed the buffer: len=%d cap=%d", b.Len(), b.Cap())
+	}
+
+	_, _ = b.Write(make([]byte, 12))
+	if b.Cap() != 16 {
+		t.Fatalf("expected compaction rather than growth, cap=%d", b.Cap())
+	}
+}
+
+func TestBufferWillReallocateNoCompaction(t *testing.T) {
+	modes := []struct {
+		name string
+		new  func() *Buffer
+	}{
+		{"stable", func() *Buffer { b := NewBuffer(16); b.SetStable(true); return b }},
+		{"resumable", func() *Buffer { b := NewBuffer(16); b.SetResumable(true); return b }},
+		{"seeked", func() *Buffer { return NewBuffer(16) }},
+		{"append", func() *Buffer { return NewAppendBuffer(16) }},
+	}
+	for _, m := range modes {
+		t.Run(m.name, func(t *testing.T) {
+			b := m.new()
+			_, _ = b.Write(make([]byte, 10))
+			if m.name == "seeked" {
+				if _, err := b.Seek(8, io.SeekStart); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				_, _ = b.Read(make([]byte, 8)) // 8 consumed, 2 unread
+			}
+			if b.WillReallocate(6) {
+				t.Fatalf("expected a write into free tail space not to reallocate")
+			}
+			if !b.WillReallocate(12) {
+				t.Fatalf("expected consumed bytes not to count as free space")
+			}
+			first := &b.buf[0]
+			_, _ = b.Write(make([]byte, 12))
+			if &b.buf[0] == first {
+				t.Fatalf("WillReallocate reported a reallocation that did not happen")
+			}
+		})
+	}
+}
+
+func TestBufferGrowthHint(t *testing.T) {
+	b := NewBuffer(16)
+	_, _ = b.Write(make([]byte, 16))
+	b.SetGrowthHint(100)
+	_ = b.WriteByte(1)
+	if b.Cap() != 100 {
+		t.Fatalf("expected hinted cap 100, got %d", b.Cap())
+	}
+
+	// The hint is consumed by one growth; the next follows the strategy.
+	_, _ = b.Write(make([]byte, 100))
+	if b.Cap() != 128 {
+		t.Fatalf("expected power-of-two cap 128 after hint was used, got %d", b.Cap())
+	}
+}
+
+func TestBufferGrowDampened(t *testing.T) {
+	const thr = DefaultGrowthThreshold
+	cases := []struct {
+		name    string
+		initial int // bytes already written into an exactly-sized buffer
+		grow    int
+		want    int
+	}{
+		{"below threshold doubles", 0, 20000, thr},
+		{"at threshold", 0, thr, thr},
+		{"just past threshold from empty", 0, thr + 1024, thr + 1024},
+		{"past threshold from full", thr, 1, thr + thr/4},
+		{"large jump wins over 1.25x", thr, thr, 2 * thr},
+		{"1.25x rounds up", thr + 1, 1, thr + 1 + (thr+1+3)/4},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBuffer(tc.initial)
+			b.SetGrowthStrategy(GrowDampened)
+			_, _ = b.Write(make([]byte, tc.initial))
+			b.Grow(tc.grow)
+			if b.Cap() != tc.want {
+				t.Fatalf("expected cap %d, got %d", tc.want, b.Cap())
+			}
+		})
+	}
+
+	b := NewBuffer(0)
+	b.SetGrowthStrategy(GrowDampened)
+	b.SetGrowthThreshold(1024)
+	b.Grow(1500)
+	if b.Cap() != 1500 {
+		t.Fatalf("expected custom threshold to apply, got cap %d", b.Cap())
+	}
+	b = NewBuffer(0)
+	b.SetGrowthStrategy(GrowDampened)
+	b.Grow(1500)
+	if b.Cap() != 2048 {
+		t.Fatalf("expected power-of-two below default threshold, got cap %d", b.Cap())
+	}
+}
+
+func TestBufferTrailingPartialRune(t *testing.T) {
+	euro := "€" // 3 bytes
+	cases := []struct {
+		in   string
+		want int
+	}{
+		{"abc", 0},
+		{"ab" + euro, 0},
+		{"ab" + euro[:1], 1},
+		{"ab" + euro[:2], 2},
+		{"😀"[:3], 3},
+		{"ab\xff", 0},
+		{"", 0},
+	}
+	for _, tc := range cases {
+		b := NewBuffer(0)
+		_, _ = b.WriteString(tc.in)
+		if got := b.TrailingPartialRune(); got != tc.want {
+			t.Fatalf("%q: expected %d, got %d", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestBufferTrailingPartial(t *testing.T) {
+	b := NewBuffer(0)
+	_, _ = b.WriteString("aaaabbbbcc")
+	if got := b.TrailingPartial(4); got != 2 {
+		t.Fatalf("expected 2 trailing bytes, got %d", got)
+	}
+	_, _ = b.WriteString("cc")
+	if got := b.TrailingPartial(4); got != 0 {
+		t.Fatalf("expected clean record boundary, got %d", got)
+	}
+}
+
+func TestBufferReadByteUnreadByte(t *testing.T) {
+	b := NewBuffer(0)
+	if err := b.UnreadByte(); err != ErrUnreadByte {
+		t.Fatalf("expected ErrUnreadByte on fresh buffer, got %v", err)
+	}
+	_, _ = b.WriteString("ab")
+	c, err := b.ReadByte()
+	if err != nil || c != 'a' {
+		t.Fatalf("ReadByte c=%q err=%v", c, err)
+	}
+	if err := b.UnreadByte(); err != nil {
+		t.Fatalf("UnreadByte: %v", err)
+	}
+	if err := b.UnreadByte(); err != ErrUnreadByte {
+		t.Fatalf("expected ErrUnreadByte on repeated unread, got %v", err)
+	}
+
+	// Draining the buffer must not lose the byte needed for UnreadByte.
+	_, _ = b.ReadByte()
+	if c, _ = b.ReadByte(); c != 'b' {
+		t.Fatalf("expected 'b', got %q", c)
+	}
+	if err := b.UnreadByte(); err != nil {
+		t.Fatalf("UnreadByte after drain: %v", err)
+	}
+	if got := b.String(); got != "b" {
+		t.Fatalf("unexpected contents after unread: %q", got)
+	}
+	_, _ = b.ReadByte()
+	if _, err := b.ReadByte(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestBufferUnreadByteSurvivesCompaction(t *testing.T) {
+	b := NewBuffer(8)
+	_, _ = b.WriteString("abcdefgh")
+	_, _ = b.ReadByte()
+	_, _ = b.ReadByte()
+	if err := b.UnreadByte(); err != nil {
+		t.Fatalf("UnreadByte: %v", err)
+	}
+	// Writing compacts consumed bytes away and invalidates UnreadByte.
+	_ = b.WriteByte('X')
+	if got := b.String(); got != "bcdefghX" {
+		t.Fatalf("unexpected contents after compaction: %q", got)
+	}
+	if err := b.UnreadByte(); err != ErrUnreadByte {
+		t.Fatalf("expected ErrUnreadByte after write, got %v", err)
+	}
+}
+
+func TestBufferReadBytesAndString(t *testing.T) {
+	b := NewBuffer(0)
+	_, _ = b.WriteString("one\ntwo\nrest")
+
+	line, err := b.ReadBytes('\n')
+	if err != nil || string(line) != "one\n" {
+		t.Fatalf("ReadBytes line=%q err=%v", line, err)
+	}
+	s, err := b.ReadString('\n')
+	if err != nil || s != "two\n" {
+		t.Fatalf("ReadString s=%q err=%v", s, err)
+	}
+
+	// The returned slice must survive a compacting write.
+	_, _ = b.WriteString("XXXXXXXXXXXX")
+	if string(line) != "one\n" {
+		t.Fatalf("ReadBytes result was clobbered: %q", line)
+	}
+
+	rest, err := b.ReadBytes(0)
+	if err != io.EOF || string(rest) != "restXXXXXXXXXXXX" {
+		t.Fatalf("expected partial data with EOF, got %q err=%v", rest, err)
+	}
+	if _, err := b.ReadString('\n'); err != io.EOF {
+		t.Fatalf("expected EOF on empty buffer, got %v", err)
+	}
+}
+
+func TestBufferReadFixedArrays(t *testing.T) {
+	b := NewBuffer(0)
+	for i := 0; i < 28; i++ {
+		_ = b.WriteByte(byte(i))
+	}
+	a4, err := b.Read4()
+	if err != nil || a4 != [4]byte{0, 1, 2, 3} {
+		t.Fatalf("Read4 a=%v err=%v", a4, err)
+	}
+	a8, err := b.Read8()
+	if err != nil || a8 != [8]byte{4, 5, 6, 7, 8, 9, 10, 11} {
+		t.Fatalf("Read8 a=%v err=%v", a8, err)
+	}
+	a16, err := b.Read16()
+	if err != nil || a16[0] != 12 || a16[15] != 27 {
+		t.Fatalf("Read16 a=%v err=%v", a16, err)
+	}
+
+	_, _ = b.WriteString("abc")
+	if _, err := b.Read4(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("Read4: expected ErrUnexpectedEOF, got %v", err)
+	}
+	if _, err := b.Read8(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("Read8: expected ErrUnexpectedEOF, got %v", err)
+	}
+	if _, err := b.Read16(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("Read16: expected ErrUnexpectedEOF, got %v", err)
+	}
+	if got := b.String(); got != "abc" {
+		t.Fatalf("short reads must not consume: %q", got)
+	}
+}
+
+func TestBufferWriteStringsAppendBytes(t *testing.T) {
+	b := NewBuffer(4)
+	_, _ = b.WriteString("xx")
+	_ = b.Next(2)
+	n, err := b.WriteStrings("<", "div", " class=", "\"a\"", ">")
+	if err != nil || n != 15 || b.String() != `<div class="a">` {
+		t.Fatalf("WriteStrings = %d, %v (%q)", n, err, b.String())
+	}
+	if b.Cap() != 16 {
+		t.Fatalf("expected a single growth to 16, got cap %d", b.Cap())
+	}
+
+	n, err = b.AppendBytes([]byte("hi"), nil, []byte("</div>"))
+	if err != nil || n != 8 || b.String() != `<div class="a">hi</div>` {
+		t.Fatalf("AppendBytes = %d, %v (%q)", n, err, b.String())
+	}
+	if n, _ := b.WriteStrings(); n != 0 {
+		t.Fatalf("expected no-op for no parts")
+	}
+}
+
+func TestBufferWriteRepeatPattern(t *testing.T) {
+	b := NewBuffer(0)
+	_, _ = b.WriteString("id:")
+	n, err := b.WriteRepeat(' ', 5)
+	if err != nil || n != 5 || b.String() != "id:     " {
+		t.Fatalf("WriteRepeat = %d, %v (%q)", n, err, b.String())
+	}
+	n, err = b.WritePattern([]byte("ab"), 4)
+	if err != nil || n != 8 || b.String() != "id:     abababab" {
+		t.Fatalf("WritePattern = %d, %v (%q)", n, err, b.String())
+	}
+	for _, count := range []int{1, 3, 7, 100} {
+		c := NewBuffer(0)
+		_, _ = c.WritePattern([]byte("xyz"), count)
+		if want := strings.Repeat("xyz", count); c.String() != want {
+			t.Fatalf("WritePattern(xyz, %d) = %q", count, c.String())
+		}
+	}
+	if n, _ := b.WriteRepeat('x', 0); n != 0 {
+		t.Fatalf("expected no-op for count 0")
+	}
+	if n, _ := b.WritePattern(nil, 3); n != 0 {
+		t.Fatalf("expected no-op for empty pattern")
+	}
+
+	limited := NewBuffer(0)
+	limited.SetMaxSize(4)
+	if _, err := limited.WriteRepeat('x', 5); err != ErrBufferFull {
+		t.Fatalf("expected ErrBufferFull, got %v", err)
+	}
+}
+
+func TestBufferWriteStringRuneLimited(t *testing.T) {
+	cases := []struct {
+		s    string
+		max  int
+		want string
+	}{
+		{"日本語テキスト", 3, "日本語"},
+		{"ab日本", 3, "ab日"},
+		{"a😀b", 2, "a😀"},
+		{"短い", 5, "短い"},
+		{"abc", 0, ""},
+		{"", 3, ""},
+		{"a\xffb", 2, "a\xff"},
+	}
+	for _, tc := range cases {
+		b := NewBuffer(0)
+		n, err := b.WriteStringRuneLimited(tc.s, tc.max)
+		if err != nil || n != len(tc.want) || b.String() != tc.want {
+			t.Fatalf("WriteStringRuneLimited(%q, %d) = %d, %v (%q); want %q", tc.s, tc.max, n, err, b.String(), tc.want)
+		}
+	}
+}
+
+func TestBufferRunes(t *testing.T) {
+	var _ io.ByteWriter = (*Buffer)(nil)
+	var _ io.RuneReader = (*Buffer)(nil)
+
+	b := NewBuffer(0)
+	for _, r := range []rune{'a', 'é', '€', '😀'} {
+		if _, err := b.WriteRune(r); err != nil {
+			t.Fatalf("WriteRune(%q): %v", r, err)
+		}
+	}
+	if n, _ := b.WriteRune(-1); n != 3 {
+		t.Fatalf("expected invalid rune encoded as RuneError (3 bytes), got %d", n)
+	}
+	if got := b.String(); got != "aé€😀�" {
+		t.Fatalf("unexpected contents: %q", got)
+	}
+
+	want := []struct {
+		r    rune
+		size int
+	}{{'a', 1}, {'é', 2}, {'€', 3}, {'😀', 4}, {utf8.RuneError, 3}}
+	for _, w := range want {
+		r, size, err := b.ReadRune()
+		if err != nil || r != w.r || size != w.size {
+			t.Fatalf("ReadRune got (%q, %d, %v), want (%q, %d)", r, size, err, w.r, w.size)
+		}
+	}
+	if _, _, err := b.ReadRune(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+
+	_, _ = b.Write([]byte{0xff, 'z'})
+	if r, size, _ := b.ReadRune(); r != utf8.RuneError || size != 1 {
+		t.Fatalf("invalid byte: got (%q, %d)", r, size)
+	}
+}
+
+func TestBufferNext(t *testing.T) {
+	b := NewBuffer(8)
+	_, _ = b.WriteString("hdrbody")
+	if got := string(b.Next(3)); got != "hdr" {
+		t.Fatalf("Next(3) = %q", got)
+	}
+	body := b.Next(100)
+	if string(body) != "body" {
+		t.Fatalf("Next capped at Len: %q", body)
+	}
+	if b.Len() != 0 || b.Next(1) != nil {
+		t.Fatalf("expected empty buffer after consuming all data")
+	}
+
+	// Documented caveat: once everything is consumed the next write reuses the
+	// backing array from the start, overwriting what Next returned.
+	_, _ = b.WriteString("ZZZZZZZ")
+	if string(body) != "ZZZZ" {
+		t.Fatalf("expected Next result to be overwritten, got %q", body)
+	}
+}
+
+func TestBufferTruncate(t *testing.T) {
+	b := NewBuffer(16)
+	_, _ = b.WriteString("xxpayload-junk")
+	_, _ = b.Read(make([]byte, 2))
+	capBefore := b.Cap()
+	b.Truncate(7)
+	if got := b.String(); got != "payload" {
+		t.Fatalf("unexpected contents: %q", got)
+	}
+	if b.Cap() != capBefore {
+		t.Fatalf("truncate changed capacity: %d -> %d", capBefore, b.Cap())
+	}
+	b.Truncate(0)
+	if b.Len() != 0 {
+		t.Fatalf("expected empty buffer, len=%d", b.Len())
+	}
+
+	for _, n := range []int{-1, 1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected panic for Truncate(%d)", n)
+				}
+			}()
+			b.Truncate(n)
+		}()
+	}
+}
+
+func TestBufferDiscard(t *testing.T) {
+	b := NewBuffer(0)
+	_, _ = b.WriteString("pad-data")
+	if n, err := b.Discard(4); err != nil || n != 4 {
+		t.Fatalf("Discard n=%d err=%v", n, err)
+	}
+	if got := b.String(); got != "data" {
+		t.Fatalf("unexpected contents: %q", got)
+	}
+	n, err := b.Discard(10)
+	if err != io.EOF || n != 4 {
+		t.Fatalf("expected short discard with EOF, n=%d err=%v", n, err)
+	}
+	if b.Len() != 0 || len(b.UnsafeBytes()) != 0 {
+		t.Fatalf("expected buffer reset after draining, raw len=%d", len(b.UnsafeBytes()))
+	}
+}
+
+func TestBufferPull(t *testing.T) {
+	b := NewBuffer(0)
+	_, _ = b.WriteString("aaabbbcc")
+
+	chunk, advance, done := b.Pull(3)
+	if done || string(chunk) != "aaa" {
+		t.Fatalf("first pull chunk=%q done=%v", chunk, done)
+	}
+	advance()
+	advance() // second call is a no-op
+
+	// Processing fails: do not advance, the same chunk is offered again.
+	chunk, _, _ = b.Pull(3)
+	if string(chunk) != "bbb" {
+		t.Fatalf("second pull chunk=%q", chunk)
+	}
+	chunk, advance, _ = b.Pull(3)
+	if string(chunk) != "bbb" {
+		t.Fatalf("expected retry of the same chunk, got %q", chunk)
+	}
+	advance()
+
+	chunk, advance, _ = b.Pull(0)
+	if string(chunk) != "cc" {
+		t.Fatalf("expected remaining data, got %q", chunk)
+	}
+	advance()
+	if _, _, done := b.Pull(3); !done {
+		t.Fatalf("expected done on empty buffer")
+	}
+}
+
+func TestBufferPeek(t *testing.T) {
+	b := NewBuffer(0)
+	_, _ = b.WriteString("\x00\x05hello")
+	hdr, err := b.Peek(2)
+	if err != nil || !bytes.Equal(hdr, []byte{0, 5}) {
+		t.Fatalf("Peek hdr=%v err=%v", hdr, err)
+	}
+	if b.Len() != 7 {
+		t.Fatalf("Peek advanced the cursor: len=%d", b.Len())
+	}
+	all, err := b.Peek(10)
+	if err != io.EOF || len(all) != 7 {
+		t.Fatalf("expected short peek with EOF, len=%d err=%v", len(all), err)
+	}
+}
+
+func TestBufferCopyTo(t *testing.T) {
+	b := NewBuffer(0)
+	_, _ = b.WriteString("abcdef")
+	var dst [4]byte
+	if n := b.CopyTo(dst[:]); n != 4 || string(dst[:]) != "abcd" {
+		t.Fatalf("CopyTo n=%d dst=%q", n, dst)
+	}
+	if n := b.CopyTo(dst[:]); n != 2 || string(dst[:2]) != "ef" {
+		t.Fatalf("CopyTo n=%d dst=%q", n, dst[:2])
+	}
+	if len(b.UnsafeBytes()) != 0 {
+		t.Fatalf("expected reset after draining")
+	}
+	if n := b.CopyTo(dst[:]); n != 0 {
+		t.Fatalf("expected 0 from empty buffer, got %d", n)
+	}
+}
+
+func TestBufferSearchAny(t *testing.T) {
+	b := NewBuffer(0)
+	_, _ = b.WriteString("xxGET /a HTTP/1.1\r\n\r\nbody")
+	_ = b.Next(2)
+
+	cases := []struct {
+		patterns   []string
+		idx, which int
+	}{
+		{[]string{"\r\n\r\n", "1\r\n"}, 14, 1},     // overlapping: the later pattern starts first
+		{[]string{"HTTP", "TTP/1", "/a"}, 4, 2},    // earliest match wins regardless of order
+		{[]string{"HTTP/1.1", "HTTP"}, 7, 0},       // tie at the same index: first pattern wins
+		{[]string{"missing", "body", "a H"}, 5, 2}, // later pattern found before an earlier one
+		{[]string{"nope", "absent"}, -1, -1},
+		{[]string{"GET", ""}, 0, 0}, // empty pattern after a match at 0
+		{[]string{"", "GET"}, 0, 0},
+	}
+	for _, tc := range cases {
+		pats := make([][]byte, len(tc.patterns))
+		for i, p := range tc.patterns {
+			pats[i] = []byte(p)
+		}
+		idx, which := b.SearchAny(pats)
+		if idx != tc.idx || which != tc.which {
+			t.Fatalf("SearchAny(%q) = (%d, %d), want (%d, %d)", tc.patterns, idx, which, tc.idx, tc.which)
+		}
+	}
+	if b.Len() != 23 {
+		t.Fatalf("SearchAny moved the cursor: len=%d", b.Len())
+	}
+}
+
+func TestBufferIndexByteFrom(t *testing.T) {
+	b := NewBuffer(0)
+	_, _ = b.WriteString("partial frame")
+	scanned := b.Len()
+	if i := b.IndexByteFrom(0, '\n'); i != -1 {
+		t.Fatalf("expected no delimiter yet, got %d", i)
+	}
+
+	_, _ = b.WriteString(" data\nnext\n")
+	i := b.IndexByteFrom(scanned, '\n')
+	if i != len("partial frame data") {
+		t.Fatalf("unexpected resumed index %d", i)
+	}
+	if j := b.IndexByteFrom(i+1, '\n'); j != i+5 {
+		t.Fatalf("expected second delimiter at %d, got %d", i+5, j)
+	}
+	if j := b.IndexByteFrom(100, '\n'); j != -1 {
+		t.Fatalf("expected -1 past end, got %d", j)
+	}
+}
+
+func TestBufferClone(t *testing.T) {
+	b := NewBuffer(0)
+	_, _ = b.WriteString("xxsnapshot")
+	_, _ = b.Discard(2)
+
+	c := b.Clone()
+	if got := c.String(); got != "snapshot" {
+		t.Fatalf("unexpected clone contents: %q", got)
+	}
+	if c.Cap() != 8 {
+		t.Fatalf("expected cap 8, got %d", c.Cap())
+	}
+	c.Bytes()[0] = 'S'
+	_, _ = c.WriteString("!")
+	_, _ = b.WriteString("?")
+	if b.String() != "snapshot?" || c.String() != "Snapshot!" {
+		t.Fatalf("clone aliases original: b=%q c=%q", b.String(), c.String())
+	}
+}
+
+func TestBufferReadAtWriteAt(t *testing.T) {
+	var _ io.ReaderAt = (*Buffer)(nil)
+	var _ io.WriterAt = (*Buffer)(nil)
+
+	b := NewBuffer(4)
+	_, _ = b.WriteString("abcdef")
+	_, _ = b.ReadByte() // cursor does not affect positional offsets
+
+	p := make([]byte, 3)
+	if n, err := b.ReadAt(p, 0); err != nil || n != 3 || string(p) != "abc" {
+		t.Fatalf("ReadAt n=%d err=%v p=%q", n, err, p)
+	}
+	if n, err := b.ReadAt(p, 4); err != io.EOF || n != 2 || string(p[:2]) != "ef" {
+		t.Fatalf("ReadAt tail n=%d err=%v", n, err)
+	}
+	if _, err := b.ReadAt(p, -1); err != ErrNegativeOffset {
+		t.Fatalf("expected ErrNegativeOffset, got %v", err)
+	}
+
+	if n, err := b.WriteAt([]byte("XY"), 2); err != nil || n != 2 {
+		t.Fatalf("WriteAt n=%d err=%v", n, err)
+	}
+	if n, err := b.WriteAt([]byte("Z"), 9); err != nil || n != 1 {
+		t.Fatalf("WriteAt extend n=%d err=%v", n, err)
+	}
+	if got := string(b.UnsafeBytes()); got != "abXYef\x00\x00\x00Z" {
+		t.Fatalf("unexpected backing contents: %q", got)
+	}
+	if got := b.String(); got != "bXYef\x00\x00\x00Z" {
+		t.Fatalf("cursor moved by positional ops: %q", got)
+	}
+}
+
+func TestBufferGrowForChunks(t *testing.T) {
+	chunks := [][]byte{
+		bytes.Repeat([]byte("a"), 100),
+		bytes.Repeat([]byte("b"), 300),
+		bytes.Repeat([]byte("c"), 50),
+	}
+	b := NewBuffer(8)
+	_, _ = b.WriteString("hdr")
+	b.GrowForChunks(chunks...)
+	backing := &b.UnsafeBytes()[:1][0]
+	capBefore := b.Cap()
+	for _, c := range chunks {
+		_, _ = b.Write(c)
+	}
+	if b.Cap() != capBefore || &b.UnsafeBytes()[0] != backing {
+		t.Fatalf("writes reallocated after GrowForChunks: cap %d -> %d", capBefore, b.Cap())
+	}
+	if b.Len() != 3+450 {
+		t.Fatalf("unexpected len %d", b.Len())
+	}
+}
+
+func TestBufferWriteAtGrowFragments(t *testing.T) {
+	b := NewBuffer(0)
+	if err := b.WriteAtGrow([]byte("tail"), 10); err != nil {
+		t.Fatalf("WriteAtGrow: %v", err)
+	}
+	if b.Len() != 14 {
+		t.Fatalf("expected assembled len 14, got %d", b.Len())
+	}
+	if !bytes.Equal(b.Bytes()[:10], make([]byte, 10)) {
+		t.Fatalf("expected zero-filled gap, got %q", b.Bytes()[:10])
+	}
+	if err := b.WriteAtGrow([]byte("head"), 0); err != nil {
+		t.Fatalf("WriteAtGrow: %v", err)
+	}
+	if got := b.String(); got != "head\x00\x00\x00\x00\x00\x00tail" {
+		t.Fatalf("unexpected assembly: %q", got)
+	}
+	if b.Len() != 14 {
+		t.Fatalf("lower write changed len: %d", b.Len())
+	}
+	if err := b.WriteAtGrow([]byte("x"), -1); err != ErrNegativeOffset {
+		t.Fatalf("expected ErrNegativeOffset, got %v", err)
+	}
+}
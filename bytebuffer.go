@@ -0,0 +1,131 @@
+package gobuff
+
+import (
+	"io"
+	"sync"
+)
+
+// ByteBuffer mirrors valyala/bytebufferpool's ByteBuffer so existing call
+// sites can switch to GoBuff by changing only the pool they obtain buffers
+// from. Callers may append to B directly, as with bytebufferpool.
+type ByteBuffer struct {
+	B []byte
+
+	buf *Buffer
+}
+
+// Len returns the number of bytes in B.
+func (b *ByteBuffer) Len() int { return len(b.B) }
+
+// Bytes returns B.
+func (b *ByteBuffer) Bytes() []byte { return b.B }
+
+// String returns B as a string.
+func (b *ByteBuffer) String() string { return string(b.B) }
+
+// Write appends p to B.
+func (b *ByteBuffer) Write(p []byte) (int, error) {
+	b.B = append(b.B, p...)
+	return len(p), nil
+}
+
+// WriteByte appends c to B.
+func (b *ByteBuffer) WriteByte(c byte) error {
+	b.B = append(b.B, c)
+	return nil
+}
+
+// WriteString appends s to B.
+func (b *ByteBuffer) WriteString(s string) (int, error) {
+	b.B = append(b.B, s...)
+	return len(s), nil
+}
+
+// Set replaces B with a copy of p.
+func (b *ByteBuffer) Set(p []byte) { b.B = append(b.B[:0], p...) }
+
+// SetString replaces B with a copy of s.
+func (b *ByteBuffer) SetString(s string) { b.B = append(b.B[:0], s...) }
+
+// Reset empties B, keeping its capacity.
+func (b *ByteBuffer) Reset() { b.B = b.B[:0] }
+
+// ReadFrom appends everything read from r to B.
+func (b *ByteBuffer) ReadFrom(r io.Reader) (int64, error) {
+	buf := b.attach()
+	n, err := buf.ReadFrom(r)
+	b.B = buf.buf
+	return n, err
+}
+
+// WriteTo writes B to w. Unlike Buffer.WriteTo, B is left intact.
+func (b *ByteBuffer) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(b.B)
+	return int64(n), err
+}
+
+// attach points the backing Buffer at B so Buffer methods see the same bytes.
+// When the Buffer has an Allocator and appends to B moved it off the
+// allocator's array, B is copied back instead, so Free only ever receives
+// arrays the allocator handed out.
+func (b *ByteBuffer) attach() *Buffer {
+	if b.buf == nil {
+		b.buf = &Buffer{}
+	}
+	buf := b.buf
+	buf.r = 0
+	buf.lastRead = 0
+	if buf.alloc != nil && !sameArray(b.B, buf.buf) {
+		buf.buf = buf.buf[:0]
+		_, _ = buf.Write(b.B)
+		return buf
+	}
+	buf.buf = b.B
+	return buf
+}
+
+// sameArray reports whether p and q start at the same array element.
+func sameArray(p, q []byte) bool {
+	return cap(p) > 0 && cap(q) > 0 && &p[:1][0] == &q[:1][0]
+}
+
+// ByteBufferPool adapts a BufferPool to the bytebufferpool.Pool API. Buffers
+// are drawn from and returned to the wrapped pool, so its buckets, calibration
+// and Stats apply unchanged.
+type ByteBufferPool struct {
+	pool   *BufferPool
+	shells sync.Pool
+}
+
+// NewByteBufferPool returns an adapter backed by p. A nil p uses a new pool
+// with default options.
+func NewByteBufferPool(p *BufferPool) *ByteBufferPool {
+	if p == nil {
+		p = NewBufferPoolWithOptions(PoolOptions{})
+	}
+	return &ByteBufferPool{pool: p}
+}
+
+// Get returns an empty ByteBuffer backed by a pooled Buffer.
+func (p *ByteBufferPool) Get() *ByteBuffer {
+	buf := p.pool.Get()
+	bb, _ := p.shells.Get().(*ByteBuffer)
+	if bb == nil {
+		bb = &ByteBuffer{}
+	}
+	bb.buf = buf
+	bb.B = buf.buf[:0]
+	return bb
+}
+
+// Put returns bb to the underlying pool. bb and its B must not be used after
+// Put returns.
+func (p *ByteBufferPool) Put(bb *ByteBuffer) {
+	if bb == nil {
+		return
+	}
+	buf := bb.attach()
+	bb.B, bb.buf = nil, nil
+	p.shells.Put(bb)
+	p.pool.Put(buf)
+}
@@ -0,0 +1,67 @@
+package gobuff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestByteBufferPoolRoutesThroughPool(t *testing.T) {
+	pool := NewBufferPoolWithOptions(PoolOptions{InitialCap: 64})
+	bp := NewByteBufferPool(pool)
+
+	bb := bp.Get()
+	if bb.Len() != 0 {
+		t.Fatalf("expected empty buffer, got %d bytes", bb.Len())
+	}
+	_, _ = bb.Write([]byte("hello "))
+	_ = bb.WriteByte('w')
+	_, _ = bb.WriteString("orld")
+	bb.B = append(bb.B, '!')
+	if got := bb.String(); got != "hello world!" {
+		t.Fatalf("unexpected contents %q", got)
+	}
+	bp.Put(bb)
+
+	s := pool.Stats()
+	if s.Gets != 1 || s.Puts != 1 {
+		t.Fatalf("expected 1 get and 1 put on the underlying pool, got %+v", s)
+	}
+
+	bb = bp.Get()
+	if bb.Len() != 0 {
+		t.Fatalf("expected reused buffer to be empty, got %q", bb.B)
+	}
+	bp.Put(bb)
+	if s := pool.Stats(); s.Gets != 2 || s.Puts != 2 {
+		t.Fatalf("expected 2 gets and 2 puts, got %+v", s)
+	}
+}
+
+func TestByteBufferReadFromWriteTo(t *testing.T) {
+	bp := NewByteBufferPool(nil)
+	bb := bp.Get()
+	defer bp.Put(bb)
+
+	bb.SetString("prefix:")
+	data := strings.Repeat("x", 5000)
+	n, err := bb.ReadFrom(strings.NewReader(data))
+	if err != nil || n != int64(len(data)) {
+		t.Fatalf("ReadFrom = %d, %v", n, err)
+	}
+	if want := "prefix:" + data; bb.String() != want {
+		t.Fatalf("unexpected contents after ReadFrom (len %d)", bb.Len())
+	}
+
+	var out bytes.Buffer
+	if _, err := bb.WriteTo(&out); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	if out.String() != bb.String() {
+		t.Fatalf("WriteTo should leave B intact")
+	}
+	bb.Reset()
+	if bb.Len() != 0 {
+		t.Fatalf("expected Reset to empty B")
+	}
+}
@@ -0,0 +1,27 @@
+package gobuff
+
+import "sync"
+
+var (
+	defaultPoolOnce sync.Once
+	defaultPool     *BufferPool
+)
+
+// DefaultPool returns the package-level pool behind Get, GetSized and Put. It
+// is created with default options on first use and cannot be reconfigured;
+// construct a BufferPool directly when tuning is needed.
+func DefaultPool() *BufferPool {
+	defaultPoolOnce.Do(func() {
+		defaultPool = NewBufferPoolWithOptions(PoolOptions{})
+	})
+	return defaultPool
+}
+
+// Get retrieves a Buffer from the default pool.
+func Get() *Buffer { return DefaultPool().Get() }
+
+// GetSized retrieves a Buffer sized for n bytes from the default pool.
+func GetSized(n int) *Buffer { return DefaultPool().GetSized(n) }
+
+// Put returns b to the default pool.
+func Put(b *Buffer) { DefaultPool().Put(b) }
@@ -0,0 +1,23 @@
+package gobuff
+
+import "testing"
+
+func TestDefaultPool(t *testing.T) {
+	if DefaultPool() != DefaultPool() {
+		t.Fatalf("expected DefaultPool to return the same pool")
+	}
+	before := DefaultPool().Stats()
+
+	b := GetSized(1000)
+	if b.Cap() < 1000 {
+		t.Fatalf("expected cap >= 1000, got %d", b.Cap())
+	}
+	_, _ = b.WriteString("x")
+	Put(b)
+	Put(Get())
+
+	after := DefaultPool().Stats()
+	if after.Gets-before.Gets != 2 || after.Puts-before.Puts != 2 {
+		t.Fatalf("expected package functions to use DefaultPool: before %+v after %+v", before, after)
+	}
+}
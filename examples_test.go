@@ -34,3 +34,11 @@ func ExampleBufferPool_Borrow() {
 	fmt.Printf("gets=%d puts=%d", stats.Gets, stats.Puts)
 	// Output: gets=1 puts=1
 }
+
+func ExampleGet() {
+	buf := Get()
+	defer Put(buf)
+	_, _ = buf.WriteString("pooled")
+	fmt.Println(buf.String())
+	// Output: pooled
+}
@@ -0,0 +1,167 @@
+package gobuff
+
+import "sync"
+
+// defaultMaxPerBucket caps each freelist when the bounded backend is enabled.
+const defaultMaxPerBucket = 1024
+
+// freelist is a mutex-guarded stack of idle buffers. Unlike sync.Pool it is
+// never cleared by the GC and can be enumerated.
+type freelist struct {
+	mu   sync.Mutex
+	bufs []*Buffer
+}
+
+func (f *freelist) pop() *Buffer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := len(f.bufs)
+	if n == 0 {
+		return nil
+	}
+	b := f.bufs[n-1]
+	f.bufs[n-1] = nil
+	f.bufs = f.bufs[:n-1]
+	return b
+}
+
+// push retains b unless the list already holds limit buffers.
+func (f *freelist) push(b *Buffer, limit int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.bufs) >= limit {
+		return false
+	}
+	f.bufs = append(f.bufs, b)
+	return true
+}
+
+func (f *freelist) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clear(f.bufs)
+	f.bufs = f.bufs[:0]
+}
+
+// drain empties the list, calling fn for each buffer it held.
+func (f *freelist) drain(fn func(*Buffer)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, b := range f.bufs {
+		fn(b)
+	}
+	clear(f.bufs)
+	f.bufs = f.bufs[:0]
+}
+
+func (f *freelist) each(fn func(*Buffer)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, b := range f.bufs {
+		fn(b)
+	}
+}
+
+// take returns an idle buffer for slot (a bucket index or smallSlot) from the
+// active backend: the bounded freelist, the shards, or pool, whose New
+// allocates when nothing is pooled.
+func (p *BufferPool) take(l *bucketLayout, pool *sync.Pool, fl *freelist, slot int) *Buffer {
+	b := p.takeIdle(l, pool, fl, slot)
+	if p.budget > 0 {
+		p.untrackCollected(b)
+		p.refundBudget(b)
+	}
+	return b
+}
+
+func (p *BufferPool) takeIdle(l *bucketLayout, pool *sync.Pool, fl *freelist, slot int) *Buffer {
+	if fl != nil {
+		if b := fl.pop(); b != nil {
+			return b
+		}
+	} else {
+		if h := p.hotList(l, slot); h != nil {
+			if b := h.pop(); b != nil {
+				return b
+			}
+		}
+		if sh := p.sharded.Load(); sh != nil && sh.layout == l {
+			if b := sh.get(slot); b != nil {
+				return b
+			}
+		}
+	}
+	return pool.Get().(*Buffer)
+}
+
+// retain stores b for slot in the active backend.
+// Buffers beyond MaxPerBucket are dropped for the GC to collect.
+func (p *BufferPool) retain(l *bucketLayout, pool *sync.Pool, fl *freelist, slot int, b *Buffer) {
+	if fl != nil {
+		if !fl.push(b, p.maxPerBucket) {
+			p.freeDrops.Add(1)
+			if p.onEvict != nil {
+				p.onEvict(b)
+			}
+			p.discard(b)
+		}
+		return
+	}
+	if h := p.hotList(l, slot); h != nil && h.push(b, p.hotLimit) {
+		return
+	}
+	if p.budget > 0 {
+		p.trackCollected(b)
+	}
+	if sh := p.sharded.Load(); sh != nil && sh.layout == l {
+		sh.put(slot, b)
+		return
+	}
+	pool.Put(b)
+}
+
+// smallFreelist returns the small-buffer freelist, or nil for the sync.Pool backend.
+func (p *BufferPool) smallFreelist() *freelist {
+	if !p.bounded {
+		return nil
+	}
+	return &p.smallFree
+}
+
+// bucketFreelist returns the freelist for bucket idx, or nil for the sync.Pool backend.
+func (l *bucketLayout) bucketFreelist(idx int) *freelist {
+	if l.free == nil {
+		return nil
+	}
+	return &l.free[idx]
+}
+
+// hotList returns the HotReserve list for slot, or nil when it is disabled.
+func (p *BufferPool) hotList(l *bucketLayout, slot int) *freelist {
+	if l.hot == nil {
+		return nil
+	}
+	if slot == smallSlot {
+		return &p.hotSmall
+	}
+	return &l.hot[slot]
+}
+
+// ForEachPooled calls fn for every idle buffer held by the pool, e.g. to wipe
+// pooled memory on shutdown with clear(b.UnsafeBytes()[:b.Cap()]), including
+// those in the PriorityReserves. Bucket buffers are only seen when the pool
+// uses BoundedFreelists; sync.Pool cannot be enumerated, so otherwise only the
+// reserves are visited. fn must not call back into the pool.
+func (p *BufferPool) ForEachPooled(fn func(*Buffer)) {
+	for i := range p.classes {
+		p.classes[i].reserve.each(fn)
+	}
+	if !p.bounded {
+		return
+	}
+	p.smallFree.each(fn)
+	l := p.layout()
+	for i := range l.free {
+		l.free[i].each(fn)
+	}
+}
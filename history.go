@@ -0,0 +1,53 @@
+package gobuff
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultHistorySize is the DefaultCapHistory ring size when HistorySize is unset.
+const defaultHistorySize = 64
+
+// CapChange records one calibration-driven change of the default capacity.
+type CapChange struct {
+	Timestamp time.Time
+	OldCap    int
+	NewCap    int
+}
+
+// capHistory is a bounded ring of CapChange entries.
+type capHistory struct {
+	mu      sync.Mutex
+	entries []CapChange
+	next    int // slot for the next entry once the ring is full
+	size    int
+}
+
+func (h *capHistory) add(c CapChange) {
+	h.mu.Lock()
+	if len(h.entries) < h.size {
+		h.entries = append(h.entries, c)
+	} else {
+		h.entries[h.next] = c
+		h.next = (h.next + 1) % h.size
+	}
+	h.mu.Unlock()
+}
+
+// snapshot returns the entries oldest first.
+func (h *capHistory) snapshot() []CapChange {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]CapChange, 0, len(h.entries))
+	out = append(out, h.entries[h.next:]...)
+	return append(out, h.entries[:h.next]...)
+}
+
+// DefaultCapHistory returns the most recent calibration changes to the default
+// capacity, oldest first. It returns nil unless DebugHistory is enabled.
+func (p *BufferPool) DefaultCapHistory() []CapChange {
+	if p.history == nil {
+		return nil
+	}
+	return p.history.snapshot()
+}
@@ -0,0 +1,38 @@
+package gobuff
+
+import "testing"
+
+func TestBufferPoolDefaultCapHistory(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:  []int{64, 256, 1024, 4096},
+		InitialCap:   64,
+		DebugHistory: true,
+		HistorySize:  3,
+	})
+	for _, size := range []int{200, 200, 1000, 4000, 60} {
+		p.Calibrate(size)
+	}
+
+	// The repeated 200 is not a change; the ring keeps the last three changes.
+	want := []CapChange{
+		{OldCap: 256, NewCap: 1024},
+		{OldCap: 1024, NewCap: 4096},
+		{OldCap: 4096, NewCap: 64},
+	}
+	got := p.DefaultCapHistory()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d entries, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i].OldCap != want[i].OldCap || got[i].NewCap != want[i].NewCap {
+			t.Fatalf("entry %d: expected %d->%d, got %d->%d", i, want[i].OldCap, want[i].NewCap, got[i].OldCap, got[i].NewCap)
+		}
+		if i > 0 && got[i].Timestamp.Before(got[i-1].Timestamp) {
+			t.Fatalf("entries out of order: %+v", got)
+		}
+	}
+
+	if NewBufferPool(0).DefaultCapHistory() != nil {
+		t.Fatalf("expected no history without DebugHistory")
+	}
+}
@@ -0,0 +1,96 @@
+package gobuff
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxLeakSites bounds how many leak stacks LeakSites retains.
+const maxLeakSites = 32
+
+// leakStackDepth is the number of frames captured per Get under leak detection.
+const leakStackDepth = 32
+
+// leakLog keeps the most recent leak stacks, oldest first.
+type leakLog struct {
+	mu    sync.Mutex
+	sites []string
+}
+
+func (l *leakLog) add(site string) {
+	l.mu.Lock()
+	if len(l.sites) == maxLeakSites {
+		copy(l.sites, l.sites[1:])
+		l.sites = l.sites[:maxLeakSites-1]
+	}
+	l.sites = append(l.sites, site)
+	l.mu.Unlock()
+}
+
+// checkout marks buf as handed out, counting it in UseAfterPuts if it was
+// written to while pooled, and arms leak tracking.
+func (p *BufferPool) checkout(buf *Buffer) {
+	if buf.pooled {
+		buf.pooled = false
+		if len(buf.buf) != 0 {
+			p.useAfterPuts.Add(1)
+			buf.Reset()
+		}
+	}
+	p.trackLeak(buf)
+}
+
+// checkin marks b as pooled and disarms leak tracking. It reports false, after
+// counting a double Put, if b is already pooled.
+func (p *BufferPool) checkin(b *Buffer) bool {
+	if b.pooled {
+		p.doublePuts.Add(1)
+		return false
+	}
+	b.pooled = true
+	runtime.SetFinalizer(b, nil)
+	return true
+}
+
+// trackLeak arms a finalizer on buf that counts it as leaked and records the
+// stack of the Get that handed it out. Put disarms it.
+func (p *BufferPool) trackLeak(buf *Buffer) {
+	pcs := make([]uintptr, leakStackDepth)
+	pcs = pcs[:runtime.Callers(2, pcs)]
+	runtime.SetFinalizer(buf, func(_ *Buffer) {
+		p.leaks.Add(1)
+		p.leakSites.add(formatStack(pcs))
+	})
+}
+
+// LeakSites returns the Get call stacks of the most recently detected leaks
+// (up to 32, oldest first). Stacks are only captured when DebugLeakDetection
+// is enabled; otherwise it returns nil.
+func (p *BufferPool) LeakSites() []string {
+	p.leakSites.mu.Lock()
+	defer p.leakSites.mu.Unlock()
+	if len(p.leakSites.sites) == 0 {
+		return nil
+	}
+	return append([]string(nil), p.leakSites.sites...)
+}
+
+func formatStack(pcs []uintptr) string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		sb.WriteString(f.Function)
+		sb.WriteString("\n\t")
+		sb.WriteString(f.File)
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(f.Line))
+		sb.WriteByte('\n')
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,89 @@
+package gobuff
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+//go:noinline
+func leakOneBuffer(p *BufferPool) {
+	_ = p.GetSized(100).WriteByte(1)
+}
+
+func TestBufferPoolLeakSites(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{DebugLeakDetection: true})
+	leakOneBuffer(p)
+	for i := 0; i < 50 && p.LeakCount() == 0; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	if p.LeakCount() == 0 {
+		t.Skip("finalizer did not run")
+	}
+	sites := p.LeakSites()
+	if len(sites) != 1 || !strings.Contains(sites[0], "leakOneBuffer") {
+		t.Fatalf("expected leak site naming leakOneBuffer, got %q", sites)
+	}
+
+	quiet := NewBufferPool(0)
+	if quiet.LeakSites() != nil {
+		t.Fatalf("expected no leak sites without leak detection")
+	}
+}
+
+func TestLeakLogBounded(t *testing.T) {
+	var l leakLog
+	for i := 0; i < maxLeakSites+8; i++ {
+		l.add(strconv.Itoa(i))
+	}
+	if len(l.sites) != maxLeakSites {
+		t.Fatalf("expected %d retained sites, got %d", maxLeakSites, len(l.sites))
+	}
+	if l.sites[0] != "8" || l.sites[maxLeakSites-1] != strconv.Itoa(maxLeakSites+7) {
+		t.Fatalf("expected the most recent sites, got first=%s last=%s", l.sites[0], l.sites[maxLeakSites-1])
+	}
+}
+
+func TestBufferPoolDoublePut(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{DebugLeakDetection: true, BoundedFreelists: true})
+	b := p.Get()
+	p.Put(b)
+	p.Put(b)
+	s := p.Stats()
+	if s.DoublePuts != 1 || s.Puts != 1 {
+		t.Fatalf("expected one double Put ignored, got %+v", s)
+	}
+	if a, c := p.Get(), p.Get(); a == c {
+		t.Fatalf("double Put handed the same buffer out twice")
+	}
+}
+
+func TestBufferPoolUseAfterPut(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{DebugLeakDetection: true, BoundedFreelists: true})
+	b := p.Get()
+	p.Put(b)
+	_, _ = b.WriteString("stale")
+
+	again := p.Get()
+	if again != b {
+		t.Fatalf("expected the same buffer from the freelist")
+	}
+	if again.Len() != 0 {
+		t.Fatalf("expected the reissued buffer to be empty, got %q", again.String())
+	}
+	if got := p.Stats().UseAfterPuts; got != 1 {
+		t.Fatalf("expected one use-after-Put, got %d", got)
+	}
+	p.Put(again)
+
+	quiet := NewBufferPoolWithOptions(PoolOptions{BoundedFreelists: true})
+	q := quiet.Get()
+	quiet.Put(q)
+	quiet.Put(q)
+	if s := quiet.Stats(); s.DoublePuts != 0 || s.Puts != 2 {
+		t.Fatalf("expected no ownership tracking without debug mode, got %+v", s)
+	}
+}
@@ -0,0 +1,57 @@
+package gobuff
+
+// MetricsSnapshot is a point-in-time view of pool counters shaped for
+// exposition systems such as Prometheus: ranging over Buckets yields one
+// labeled series per size class. Counters are cumulative since the pool was
+// created or ResetStats was last called, except where noted; DefaultCap is a
+// gauge.
+type MetricsSnapshot struct {
+	Gets         int64
+	Puts         int64
+	Allocs       int64
+	Calibrations int64
+	Leaks        int64
+	DefaultCap   int64
+	Buckets      []BucketMetrics
+}
+
+// BucketMetrics holds the counters of one size class.
+type BucketMetrics struct {
+	// Size is the bucket's buffer capacity, suitable as a label value.
+	Size int
+	// Gets counts Get/GetSized requests whose size falls in this class,
+	// including small-pool requests and, for the largest class, oversized ones.
+	Gets int64
+	// Puts counts buffers returned in this class; it is the SizeHistogram
+	// count and is not cleared by ResetStats.
+	Puts int64
+	// Allocs counts buffers allocated for this bucket. Small-pool
+	// allocations are counted only in MetricsSnapshot.Allocs.
+	Allocs int64
+}
+
+// MetricsSnapshot returns global and per-size-class counters. Every value is
+// read with an atomic load, so it is safe to call concurrently with Get and
+// Put, e.g. from a scrape handler; the snapshot is not taken atomically as a
+// whole, so related counters may differ by in-flight operations.
+func (p *BufferPool) MetricsSnapshot() MetricsSnapshot {
+	l := p.layout()
+	s := MetricsSnapshot{
+		Gets:         p.gets.Load(),
+		Puts:         p.puts.Load(),
+		Allocs:       p.allocs.Load(),
+		Calibrations: p.calibrations.Load(),
+		Leaks:        p.leaks.Load(),
+		DefaultCap:   p.defaultCap.Load(),
+		Buckets:      make([]BucketMetrics, len(l.sizes)),
+	}
+	for i, size := range l.sizes {
+		s.Buckets[i] = BucketMetrics{
+			Size:   size,
+			Gets:   l.gets[i].Load(),
+			Puts:   l.hist[i].Load(),
+			Allocs: l.allocs[i].Load(),
+		}
+	}
+	return s
+}
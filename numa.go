@@ -0,0 +1,55 @@
+package gobuff
+
+import "sync/atomic"
+
+// numaNode holds the bucket pools and counters for a single NUMA node.
+type numaNode struct {
+	set  atomic.Pointer[bucketSet]
+	_pad [cacheLineSize]byte
+	gets atomic.Int64
+	puts atomic.Int64
+}
+
+// NodeStats reports per-NUMA-node traffic for pools created with NUMAAware.
+type NodeStats struct {
+	Node int
+	Gets int64
+	Puts int64
+}
+
+func (p *BufferPool) initNUMA(l *bucketLayout) {
+	nodes := numaNodeCount()
+	if nodes <= 0 {
+		return
+	}
+	p.numa = make([]numaNode, nodes)
+	for i := range p.numa {
+		p.numa[i].set.Store(p.newBucketSet(l))
+	}
+}
+
+// currentNode returns the node for the calling CPU, falling back to node 0
+// when the node cannot be determined.
+func (p *BufferPool) currentNode() *numaNode {
+	idx := currentNUMANode()
+	if idx < 0 || idx >= len(p.numa) {
+		idx = 0
+	}
+	return &p.numa[idx]
+}
+
+// NodeStats returns per-node counters, or nil when NUMA placement is disabled.
+func (p *BufferPool) NodeStats() []NodeStats {
+	if len(p.numa) == 0 {
+		return nil
+	}
+	out := make([]NodeStats, len(p.numa))
+	for i := range p.numa {
+		out[i] = NodeStats{
+			Node: i,
+			Gets: p.numa[i].gets.Load(),
+			Puts: p.numa[i].puts.Load(),
+		}
+	}
+	return out
+}
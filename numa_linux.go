@@ -0,0 +1,43 @@
+//go:build (linux && amd64) || (linux && arm64)
+// +build linux,amd64 linux,arm64
+
+package gobuff
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// numaNodeCount returns the number of possible NUMA nodes, or 1 when the
+// topology cannot be read.
+func numaNodeCount() int {
+	data, err := os.ReadFile("/sys/devices/system/node/possible")
+	if err != nil {
+		return 1
+	}
+	// Format is a range list such as "0" or "0-3"; the last value is the highest node.
+	spec := strings.TrimSpace(string(data))
+	if i := strings.LastIndexAny(spec, "-,"); i >= 0 {
+		spec = spec[i+1:]
+	}
+	last, err := strconv.Atoi(spec)
+	if err != nil || last < 0 {
+		return 1
+	}
+	return last + 1
+}
+
+// currentNUMANode returns the NUMA node of the CPU the caller is running on,
+// or -1 if getcpu(2) fails.
+func currentNUMANode() int {
+	var cpu, node uint32
+	_, _, errno := syscall.RawSyscall(sysGetcpu,
+		uintptr(unsafe.Pointer(&cpu)), uintptr(unsafe.Pointer(&node)), 0)
+	if errno != 0 {
+		return -1
+	}
+	return int(node)
+}
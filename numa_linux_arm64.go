@@ -0,0 +1,4 @@
+package gobuff
+
+// sysGetcpu is the getcpu(2) syscall number; package syscall does not export it for every architecture.
+const sysGetcpu = 168
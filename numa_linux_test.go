@@ -0,0 +1,44 @@
+//go:build (linux && amd64) || (linux && arm64)
+// +build linux,amd64 linux,arm64
+
+package gobuff
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestBufferPoolNUMANodeSelection(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes: []int{64, 256, 1024},
+		NUMAAware:   true,
+	})
+	if len(p.NodeStats()) == 0 {
+		t.Fatalf("expected per-node pools on linux")
+	}
+
+	const workers = 8
+	const iters = 256
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < iters; j++ {
+				b := p.GetSized((j * id) % 2000)
+				_ = b.WriteByte(1)
+				p.Put(b)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var gets, puts int64
+	for _, ns := range p.NodeStats() {
+		gets += ns.Gets
+		puts += ns.Puts
+	}
+	if gets != workers*iters || puts != workers*iters {
+		t.Fatalf("node stats mismatch: gets=%d puts=%d", gets, puts)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux || (!amd64 && !arm64)
+// +build !linux !amd64,!arm64
+
+package gobuff
+
+// numaNodeCount reports zero nodes, disabling NUMA placement on unsupported platforms.
+func numaNodeCount() int { return 0 }
+
+func currentNUMANode() int { return -1 }
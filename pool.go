@@ -1,10 +1,14 @@
 package gobuff
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"runtime"
 	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 var defaultBucketSizes = []int{64, 128, 256, 512, 1024, 2048, 4096, 8192, 16384, 32768, 65536}
@@ -13,6 +17,10 @@ const cacheLineSize = 64
 const defaultPercentile = 0.95
 const defaultCalibrateThreshold = 42000
 
+// ErrInvalidConfig is wrapped by the errors NewBufferPoolWithOptionsErr
+// returns for rejected PoolOptions.
+var ErrInvalidConfig = errors.New("gobuff: invalid pool configuration")
+
 func (p *BufferPool) _keepPadding() {
 	_ = cacheLineSize
 	_ = p._pad0
@@ -25,25 +33,61 @@ func (p *BufferPool) _keepPadding() {
 //   - Auto-calibration of the default bucket based on observed usage.
 //   - Optional leak detection via finalizers (debug only; avoid in hot paths).
 type BufferPool struct {
-	sizes        []int
-	buckets      []sync.Pool
-	smallPool    sync.Pool
-	_pad0        [cacheLineSize]byte // isolate pools from counters
+	set          atomic.Pointer[bucketSet] // also carries the current bucketLayout
+	reconfig     sync.Mutex                // serializes Drain and SetBucketSizes
+	_pad0        [cacheLineSize]byte       // isolate pools from counters
 	defaultCap   atomic.Int64
 	observeEvery int64
 	observed     atomic.Int64
-	bucketHits   []atomic.Int64
+	adaptive     bool
+	nextObserve  atomic.Int64
+	calibrating  atomic.Bool // guards recalibratePercentile
 	percentile   float64
 	calibrateThr int64
 	_pad1        [cacheLineSize]byte // isolate counters from stats
-	smallLimit   int
+	smallOpt     int                 // SmallLimit option; 0 derives the limit from the buckets
 	debugLeaks   bool
+	warnUnread   bool
+	onUnreadPut  func(int)
+	unreadPuts   atomic.Int64
+	onReset      func(*Buffer)
+	zeroOnPut    bool
+	maxCap       int
+	oversize     atomic.Int64
+	oversizeGets atomic.Int64
+	shrinkFactor float64
+	shrinks      atomic.Int64
 	leaks        atomic.Int64
+	leakSites    leakLog
+	doublePuts   atomic.Int64
+	leases       atomic.Uint64
+	useAfterPuts atomic.Int64
 	gets         atomic.Int64
 	puts         atomic.Int64
 	allocs       atomic.Int64
 	calibrations atomic.Int64
 	metrics      func(Stats)
+	onCalibrate  func(old, new int, reason string)
+	history      *capHistory // non-nil when DebugHistory is enabled
+	numa         []numaNode
+	sharded      atomic.Pointer[shardedSet]
+	throughput   *throughputRing
+	bounded      bool // BoundedFreelists
+	smallFree    freelist
+	hotSmall     freelist
+	hotLimit     int
+	maxPerBucket int
+	onEvict      func(*Buffer)
+	freeDrops    atomic.Int64
+	classes      []priorityClass
+	reserveCap   atomic.Int64
+	reserveFixed bool // PriorityReserveCap was set; SetBucketSizes keeps it
+	growth       GrowthStrategy
+	growthLimit  int
+	alloc        Allocator
+	budget       int64        // CapacityBudget; 0 disables tracking
+	pooledCap    atomic.Int64 // capacity charged by idle buffers
+	budgetDrops  atomic.Int64
 }
 
 // PoolOptions configures a BufferPool.
@@ -53,11 +97,18 @@ type PoolOptions struct {
 	BucketSizes []int
 	// InitialCap sets the default capacity for Get().
 	InitialCap int
-	// DebugLeakDetection enables runtime finalizers that count leaked buffers.
+	// DebugLeakDetection enables runtime finalizers that count leaked buffers
+	// and capture the stack of each Get, reported by LeakSites. It also tracks
+	// buffer ownership to catch double Puts and writes after Put (see
+	// Stats.DoublePuts and Stats.UseAfterPuts). It is costly.
 	DebugLeakDetection bool
 	// ObserveEvery controls how many Put operations are sampled before auto-calibration runs.
 	// If zero or negative, a default of 4096 is used.
 	ObserveEvery int
+	// AdaptiveObserve stretches the ObserveEvery interval as observations
+	// accumulate (to 1/8 of the running total), so busy pools recalibrate
+	// logarithmically rather than linearly often.
+	AdaptiveObserve bool
 	// SmallLimit configures the cutoff (in bytes) for the fast small-buffer pool.
 	// If zero or negative, a default based on the smallest bucket is used (min(256, smallest bucket)).
 	SmallLimit int
@@ -65,8 +116,99 @@ type PoolOptions struct {
 	Percentile float64
 	// CalibrateThreshold sets the number of observed puts before percentile calibration. Default 42000.
 	CalibrateThreshold int64
+	// MaxCap, if positive, makes Put drop buffers whose capacity exceeds it
+	// instead of pooling them, so rare oversized buffers are left to the GC.
+	// Drops are counted in Stats.OversizeDrops.
+	MaxCap int
+	// ShrinkFactor, if positive, makes Put reallocate a buffer down to the size
+	// class Get handed it out for when its capacity exceeds ShrinkFactor times
+	// that size, so a one-off large use does not pin a large array in the
+	// pool. Unlike MaxCap the buffer is kept. Shrinks are counted in
+	// Stats.Shrinks.
+	ShrinkFactor float64
+	// GrowthStrategy, if not GrowPowerOfTwo, is applied to every buffer handed
+	// out by Get/GetSized, overriding any strategy set on it earlier.
+	GrowthStrategy GrowthStrategy
+	// GrowthThreshold sets the GrowDampened threshold for pooled buffers.
+	// Defaults to DefaultGrowthThreshold.
+	GrowthThreshold int
 	// Metrics, if provided, is invoked on calibration with a snapshot of Stats.
 	Metrics func(Stats)
+	// OnCalibrate, if provided, is invoked after Metrics on every calibration
+	// with the previous and new default capacity and the reason: one of
+	// CalibrateManual, CalibratePercentile or CalibratePrime.
+	OnCalibrate func(old, new int, reason string)
+	// DebugHistory records each calibration that changes the default capacity
+	// in a bounded ring, read with DefaultCapHistory, for post-mortems.
+	DebugHistory bool
+	// HistorySize sets how many DebugHistory entries are kept. Default 64.
+	HistorySize int
+	// DebugWarnUnreadOnPut counts Puts of buffers that still hold unread data,
+	// which usually means a caller returned a buffer too early. See Stats.UnreadPuts.
+	DebugWarnUnreadOnPut bool
+	// OnUnreadPut, if set with DebugWarnUnreadOnPut, is called with the number of
+	// unread bytes being discarded.
+	OnUnreadPut func(unread int)
+	// OnReset, if set, is called by Put after the buffer is Reset and before it
+	// is pooled, to clear per-buffer state such as field tracking or a custom
+	// growth strategy, or metadata callers associate with the buffer.
+	OnReset func(*Buffer)
+	// ZeroOnPut makes Put (and PutPriority) overwrite the buffer's whole
+	// backing array with zeros before pooling or dropping it, so secrets do
+	// not linger for the next user, including bytes already drained, Reset or
+	// Truncated. It costs a memclr of the full capacity on each Put. Off by
+	// default.
+	ZeroOnPut bool
+	// BoundedFreelists retains idle buffers in mutex-guarded freelists instead of
+	// sync.Pool. Retained buffers survive GC and can be visited with ForEachPooled.
+	BoundedFreelists bool
+	// PriorityReserves pre-allocates a private reserve of buffers per priority
+	// class (indexed by class) for GetPriority. Classes with 0 share the buckets.
+	PriorityReserves []int
+	// PriorityReserveCap sets the capacity of reserve buffers. Defaults to InitialCap's bucket.
+	PriorityReserveCap int
+	// HotReserve keeps up to this many recently Put buffers per bucket (and for
+	// the small pool) strongly referenced, checked by Get before sync.Pool, so
+	// a steady working set survives GC instead of being reallocated after each
+	// cycle. It sets a memory floor of roughly HotReserve buffers per bucket.
+	// Ignored with BoundedFreelists, which already retain across GC.
+	HotReserve int
+	// MaxPerBucket caps how many idle buffers each BoundedFreelists bucket keeps
+	// (default 1024). Excess buffers are dropped and counted in Stats.FreelistDrops.
+	MaxPerBucket int
+	// OnEvict, if set, is called with each buffer dropped by MaxPerBucket.
+	OnEvict func(*Buffer)
+	// ThroughputInterval, if positive, starts a background ticker that records
+	// per-interval get/put counts; see RecentThroughput. Stop it with Close.
+	ThroughputInterval time.Duration
+	// ThroughputWindows sets how many intervals are retained. Default 60.
+	ThroughputWindows int
+	// Sharded splits each bucket into GOMAXPROCS shards, each P preferring its
+	// own; Get tries the local shard and then its neighbour before allocating.
+	// It targets contention in very wide (many-core) deployments.
+	Sharded bool
+	// NUMAAware (experimental, Linux only) keeps a separate set of buckets per
+	// NUMA node and routes Get/Put to the node of the current CPU. Placement is
+	// best-effort since goroutines may migrate between calls. Ignored elsewhere.
+	NUMAAware bool
+	// StrictConfig makes NewBufferPoolWithOptionsErr reject options it would
+	// otherwise silently replace with defaults: BucketSizes with no positive
+	// entry, a Percentile outside (0, 1], or a negative CalibrateThreshold.
+	// It has no effect on NewBufferPoolWithOptions.
+	StrictConfig bool
+	// Allocator, if set, supplies the backing arrays of buffers the pool
+	// creates, and receives arrays the pool drops (MaxCap, ShrinkFactor and
+	// MaxPerBucket evictions). Buffers released by sync.Pool during GC cannot
+	// be freed, so pair it with BoundedFreelists when memory must be returned.
+	Allocator Allocator
+	// CapacityBudget, if positive, bounds the total capacity of idle buffers
+	// the pool retains: Put drops a buffer that would take the total past it,
+	// counting it in Stats.BudgetDrops, and Prewarm and PrimeFromSamples stop
+	// filling at it. The running total is reported as Stats.PooledCap. With
+	// the sync.Pool backend, buffers the GC releases are refunded by a
+	// finalizer once collected, so the total can briefly overstate what is
+	// held; use BoundedFreelists for exact accounting.
+	CapacityBudget int64
 }
 
 // NewBufferPool initializes a pool that produces empty Buffers with the given initial capacity.
@@ -75,6 +217,31 @@ func NewBufferPool(initialCap int) *BufferPool {
 	return NewBufferPoolWithOptions(PoolOptions{InitialCap: initialCap})
 }
 
+// NewBufferPoolWithOptionsErr is like NewBufferPoolWithOptions but, when
+// opts.StrictConfig is set, returns an error wrapping ErrInvalidConfig instead
+// of substituting defaults for invalid settings.
+func NewBufferPoolWithOptionsErr(opts PoolOptions) (*BufferPool, error) {
+	if opts.StrictConfig {
+		if err := opts.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return NewBufferPoolWithOptions(opts), nil
+}
+
+func (o PoolOptions) validate() error {
+	if len(o.BucketSizes) > 0 && len(normalizeSizes(o.BucketSizes)) == 0 {
+		return fmt.Errorf("%w: BucketSizes %v has no positive size", ErrInvalidConfig, o.BucketSizes)
+	}
+	if o.Percentile < 0 || o.Percentile > 1 {
+		return fmt.Errorf("%w: Percentile %v outside (0, 1]", ErrInvalidConfig, o.Percentile)
+	}
+	if o.CalibrateThreshold < 0 {
+		return fmt.Errorf("%w: negative CalibrateThreshold %d", ErrInvalidConfig, o.CalibrateThreshold)
+	}
+	return nil
+}
+
 // NewBufferPoolWithOptions constructs a BufferPool with optional bucket sizing and leak detection.
 func NewBufferPoolWithOptions(opts PoolOptions) *BufferPool {
 	sizes := normalizeSizes(opts.BucketSizes)
@@ -83,50 +250,201 @@ func NewBufferPoolWithOptions(opts PoolOptions) *BufferPool {
 	}
 
 	p := &BufferPool{
-		sizes:        sizes,
 		defaultCap:   atomic.Int64{},
 		debugLeaks:   opts.DebugLeakDetection,
+		warnUnread:   opts.DebugWarnUnreadOnPut,
+		onUnreadPut:  opts.OnUnreadPut,
+		onReset:      opts.OnReset,
+		zeroOnPut:    opts.ZeroOnPut,
+		maxCap:       opts.MaxCap,
+		shrinkFactor: opts.ShrinkFactor,
+		growth:       opts.GrowthStrategy,
+		growthLimit:  opts.GrowthThreshold,
+		alloc:        opts.Allocator,
+		budget:       opts.CapacityBudget,
 		observeEvery: 4096,
-		bucketHits:   make([]atomic.Int64, len(sizes)),
+		bounded:      opts.BoundedFreelists,
 		percentile:   defaultPercentile,
 		calibrateThr: defaultCalibrateThreshold,
 		metrics:      opts.Metrics,
+		onCalibrate:  opts.OnCalibrate,
 	}
 	p._keepPadding()
 	if opts.ObserveEvery > 0 {
 		p.observeEvery = int64(opts.ObserveEvery)
 	}
+	p.adaptive = opts.AdaptiveObserve
+	p.nextObserve.Store(p.observeEvery)
 	if opts.Percentile > 0 && opts.Percentile <= 1 {
 		p.percentile = opts.Percentile
 	}
 	if opts.CalibrateThreshold > 0 {
 		p.calibrateThr = opts.CalibrateThreshold
 	}
-	p.smallLimit = minInt(256, sizes[0])
 	if opts.SmallLimit > 0 {
-		p.smallLimit = opts.SmallLimit
+		p.smallOpt = opts.SmallLimit
 	}
 	p.defaultCap.Store(int64(chooseCap(sizes, opts.InitialCap)))
 
-	p.buckets = make([]sync.Pool, len(sizes))
-	for i, size := range sizes {
-		capacity := size
-		p.buckets[i] = sync.Pool{
-			New: func() any {
-				p.allocs.Add(1)
-				return NewBuffer(capacity)
-			},
+	if opts.HotReserve > 0 && !opts.BoundedFreelists {
+		p.hotLimit = opts.HotReserve
+	}
+	l := p.newBucketLayout(sizes)
+	p.set.Store(p.newBucketSet(l))
+	if opts.BoundedFreelists {
+		p.maxPerBucket = defaultMaxPerBucket
+		if opts.MaxPerBucket > 0 {
+			p.maxPerBucket = opts.MaxPerBucket
 		}
+		p.onEvict = opts.OnEvict
 	}
-	p.smallPool = sync.Pool{
-		New: func() any {
-			p.allocs.Add(1)
-			return NewBuffer(p.smallLimit)
-		},
+	if opts.Sharded {
+		p.sharded.Store(p.newShardedSet(l, runtime.GOMAXPROCS(0)))
+	}
+	if opts.NUMAAware {
+		p.initNUMA(l)
+	}
+	p.initPriorities(opts.PriorityReserves, opts.PriorityReserveCap)
+	if opts.DebugHistory {
+		p.history = &capHistory{size: defaultHistorySize}
+		if opts.HistorySize > 0 {
+			p.history.size = opts.HistorySize
+		}
+	}
+	if opts.ThroughputInterval > 0 {
+		p.throughput = newThroughputRing(p, opts.ThroughputInterval, opts.ThroughputWindows)
 	}
 	return p
 }
 
+// bucketLayout is one bucket configuration together with the per-bucket
+// state indexed by it. SetBucketSizes replaces it as a unit. Every bucketSet
+// and shardedSet records the layout it was built for, so an operation indexes
+// consistently by the layout of the set it loaded, even while a
+// reconfiguration is in flight.
+type bucketLayout struct {
+	sizes      []int
+	smallLimit int            // requests up to this size use the small pool
+	hits       []atomic.Int64 // Puts since the last percentile calibration
+	allocs     []atomic.Int64 // cumulative New allocations per bucket
+	gets       []atomic.Int64 // cumulative Gets per requested size class
+	hist       []atomic.Int64 // cumulative per-bucket Put counts; never reset by calibration
+	free       []freelist     // non-nil when BoundedFreelists is enabled
+	hot        []freelist     // non-nil when HotReserve is set
+}
+
+func (p *BufferPool) newBucketLayout(sizes []int) *bucketLayout {
+	l := &bucketLayout{
+		sizes:      sizes,
+		smallLimit: minInt(256, sizes[0]),
+		hits:       make([]atomic.Int64, len(sizes)),
+		allocs:     make([]atomic.Int64, len(sizes)),
+		gets:       make([]atomic.Int64, len(sizes)),
+		hist:       make([]atomic.Int64, len(sizes)),
+	}
+	if p.smallOpt > 0 {
+		l.smallLimit = p.smallOpt
+	}
+	if p.bounded {
+		l.free = make([]freelist, len(sizes))
+	}
+	if p.hotLimit > 0 {
+		l.hot = make([]freelist, len(sizes))
+	}
+	return l
+}
+
+// index returns the smallest bucket holding size, or the largest bucket.
+func (l *bucketLayout) index(size int) int {
+	if size <= 0 {
+		return 0
+	}
+	for i, s := range l.sizes {
+		if size <= s {
+			return i
+		}
+	}
+	return len(l.sizes) - 1
+}
+
+// layout returns the current bucket layout.
+func (p *BufferPool) layout() *bucketLayout {
+	return p.set.Load().layout
+}
+
+// bucketSet is one generation of pooled storage. Drain swaps in a fresh set so
+// everything held by the old one is left to the GC.
+type bucketSet struct {
+	layout  *bucketLayout
+	buckets []sync.Pool
+	small   sync.Pool
+}
+
+// newBucketSet builds empty pools for l whose New funcs allocate bucket-sized buffers.
+func (p *BufferPool) newBucketSet(l *bucketLayout) *bucketSet {
+	s := &bucketSet{layout: l, buckets: make([]sync.Pool, len(l.sizes))}
+	for i, size := range l.sizes {
+		idx, capacity := i, size
+		s.buckets[i].New = func() any {
+			p.allocs.Add(1)
+			l.allocs[idx].Add(1)
+			return p.newBuffer(capacity)
+		}
+	}
+	s.small.New = func() any {
+		p.allocs.Add(1)
+		return p.newBuffer(l.smallLimit)
+	}
+	return s
+}
+
+// pools returns the bucket set serving the calling goroutine: the current
+// NUMA node's set when NUMA placement is enabled, otherwise the shared one.
+// The node is nil when NUMA placement is disabled.
+func (p *BufferPool) pools() (*bucketSet, *numaNode) {
+	if len(p.numa) == 0 {
+		return p.set.Load(), nil
+	}
+	n := p.currentNode()
+	return n.set.Load(), n
+}
+
+// NewBufferPoolForWaste builds a pool whose buckets span minSize..maxSize bytes with a
+// geometric factor of 1/(1-maxWaste), so a buffer handed out for any size in
+// range wastes at most maxWaste (a fraction, e.g. 0.25) of its capacity.
+// maxWaste outside (0, 1) falls back to power-of-two buckets (50% waste).
+func NewBufferPoolForWaste(minSize, maxSize int, maxWaste float64) *BufferPool {
+	return NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes: wasteBucketSizes(minSize, maxSize, maxWaste),
+	})
+}
+
+func wasteBucketSizes(minSize, maxSize int, maxWaste float64) []int {
+	if minSize <= 0 {
+		minSize = 1
+	}
+	if maxSize < minSize {
+		maxSize = minSize
+	}
+	factor := 2.0
+	if maxWaste > 0 && maxWaste < 1 {
+		factor = 1 / (1 - maxWaste)
+	}
+	sizes := []int{minSize}
+	for s := minSize; s < maxSize; {
+		next := int(float64(s) * factor)
+		if next <= s {
+			next = s + 1
+		}
+		if next > maxSize {
+			next = maxSize
+		}
+		sizes = append(sizes, next)
+		s = next
+	}
+	return sizes
+}
+
 // Get retrieves a Buffer using the pool's default capacity.
 func (p *BufferPool) Get() *Buffer {
 	p.gets.Add(1)
@@ -139,6 +457,19 @@ func (p *BufferPool) GetSized(n int) *Buffer {
 	return p.getSized(n)
 }
 
+// GetZeroed retrieves a buffer holding n zero bytes (Len() == n), e.g. as
+// scratch space for decompression. The bytes are cleared explicitly, so no
+// data from a previous user of the backing array is visible.
+func (p *BufferPool) GetZeroed(n int) *Buffer {
+	if n < 0 {
+		n = 0
+	}
+	buf := p.GetSized(n)
+	buf.buf = buf.buf[:n]
+	clear(buf.buf)
+	return buf
+}
+
 // Borrow returns a buffer and a release function that must be called to return it to the pool.
 // This is useful for zero-copy workflows while keeping lifetime management explicit.
 func (p *BufferPool) Borrow(n int) (*Buffer, func()) {
@@ -147,16 +478,123 @@ func (p *BufferPool) Borrow(n int) (*Buffer, func()) {
 	return buf, func() { p.Put(buf) }
 }
 
+// BorrowContext is like Borrow but also Puts the buffer when ctx is done if
+// release has not been called by then, as a safety net against leaks in
+// request-scoped code. release is idempotent and unregisters the context hook.
+// Each call allocates a few closures and registers with ctx via
+// context.AfterFunc, which runs the auto-return on its own goroutine; the
+// caller must not touch the buffer after ctx is done.
+func (p *BufferPool) BorrowContext(ctx context.Context, n int) (*Buffer, func()) {
+	p.gets.Add(1)
+	buf := p.getSized(n)
+	var once sync.Once
+	put := func() { once.Do(func() { p.Put(buf) }) }
+	stop := context.AfterFunc(ctx, put)
+	return buf, func() {
+		stop()
+		put()
+	}
+}
+
+// Lease describes a buffer handed out by BorrowTraced.
+type Lease struct {
+	// ID is unique per pool, assigned in borrow order starting at 1.
+	ID uint64
+	// BucketSize is the size class that served the request: the small-pool
+	// limit or the chosen bucket's size.
+	BucketSize int
+
+	release func()
+}
+
+// Release returns the leased buffer to the pool. Calls after the first are
+// no-ops, including on copies of the Lease.
+func (l Lease) Release() {
+	if l.release != nil {
+		l.release()
+	}
+}
+
+// BorrowTraced is like Borrow but also returns a Lease carrying an ID and the
+// serving bucket size, for tracing buffer lifecycles.
+func (p *BufferPool) BorrowTraced(n int) (*Buffer, Lease) {
+	p.gets.Add(1)
+	buf := p.getSized(n)
+	var once sync.Once
+	return buf, Lease{
+		ID:         p.leases.Add(1),
+		BucketSize: p.sizeClass(n),
+		release:    func() { once.Do(func() { p.Put(buf) }) },
+	}
+}
+
+// sizeClass returns the capacity class getSized uses for a request of n bytes.
+func (p *BufferPool) sizeClass(n int) int {
+	l := p.layout()
+	if n <= l.smallLimit {
+		return l.smallLimit
+	}
+	if n > l.sizes[len(l.sizes)-1] {
+		return oversizeClass(n)
+	}
+	return l.sizes[l.index(n)]
+}
+
+// SplitHeaderBody consumes src, returning its first headerLen unread bytes as
+// header and copying the remainder into a pooled body buffer. header aliases
+// src and stays valid until src is next written to, Reset, or Put; body should
+// be returned with Put when done. headerLen is clamped to src.Len().
+func (p *BufferPool) SplitHeaderBody(src *Buffer, headerLen int) (header []byte, body *Buffer) {
+	if headerLen < 0 {
+		headerLen = 0
+	}
+	if n := src.Len(); headerLen > n {
+		headerLen = n
+	}
+	start := src.r
+	header = src.buf[start : start+headerLen : start+headerLen]
+	rest := src.buf[start+headerLen:]
+	body = p.GetSized(len(rest))
+	_, _ = body.Write(rest)
+	// Leave the consumed bytes in place so header stays valid until src is reused.
+	src.r = len(src.buf)
+	src.lastRead = 0
+	return header, body
+}
+
+// Fragment drains src into pooled buffers of chunkSize bytes each, advancing
+// src's cursor, and returns them in order. The final fragment holds the
+// remainder and may be shorter. Each fragment is independent and should be
+// returned with Put. It returns nil if src is empty or chunkSize <= 0.
+func (p *BufferPool) Fragment(src *Buffer, chunkSize int) []*Buffer {
+	if chunkSize <= 0 || src.Len() == 0 {
+		return nil
+	}
+	frags := make([]*Buffer, 0, (src.Len()+chunkSize-1)/chunkSize)
+	for src.Len() > 0 {
+		frag := p.GetSized(chunkSize)
+		_, _ = frag.Write(src.Next(chunkSize))
+		frags = append(frags, frag)
+	}
+	return frags
+}
+
 // Calibrate adjusts the default capacity to the nearest bucket for the observed size.
 func (p *BufferPool) Calibrate(observed int) {
 	if observed <= 0 {
 		return
 	}
-	p.defaultCap.Store(int64(chooseCap(p.sizes, observed)))
-	p.calibrations.Add(1)
-	if p.metrics != nil {
-		p.metrics(p.Stats())
-	}
+	p.setCalibratedCap(chooseCap(p.layout().sizes, observed), CalibrateManual)
+}
+
+// WithDefaultCap sets the default capacity used by Get to the bucket for
+// capacity while fn runs, restoring the previous value afterwards even if fn
+// panics. Calibration running concurrently may still adjust the default in the
+// meantime; the restore overwrites any such change.
+func (p *BufferPool) WithDefaultCap(capacity int, fn func()) {
+	prev := p.defaultCap.Swap(int64(chooseCap(p.layout().sizes, capacity)))
+	defer p.defaultCap.Store(prev)
+	fn()
 }
 
 // LeakCount returns the number of buffers that were garbage-collected without being returned when leak detection is enabled.
@@ -164,68 +602,235 @@ func (p *BufferPool) LeakCount() int64 {
 	return p.leaks.Load()
 }
 
-// Put resets and returns the Buffer to an appropriate bucket.
+// Put resets and returns the Buffer to an appropriate bucket. Resumable and
+// append-only modes (SetResumable, Seek, NewAppendBuffer) are cleared.
 func (p *BufferPool) Put(b *Buffer) {
 	if b == nil {
 		return
 	}
+	if p.debugLeaks && !p.checkin(b) {
+		return
+	}
 	p.puts.Add(1)
-	if p.debugLeaks {
-		runtime.SetFinalizer(b, nil)
+	if p.recycle(b) {
+		p.store(b)
+	}
+}
+
+// recycle applies the per-Put checks and resets b for reuse: the unread-data
+// warning, ZeroOnPut, the MaxCap drop, Reset, clearing per-use modes, and
+// OnReset. It reports false if b was dropped instead.
+func (p *BufferPool) recycle(b *Buffer) bool {
+	if p.warnUnread {
+		if unread := b.Len(); unread > 0 {
+			p.unreadPuts.Add(1)
+			if p.onUnreadPut != nil {
+				p.onUnreadPut(unread)
+			}
+		}
+	}
+	if p.zeroOnPut {
+		clear(b.buf[:cap(b.buf)]) // drained bytes sit beyond len
+	}
+	if p.maxCap > 0 && cap(b.buf) > p.maxCap {
+		p.oversize.Add(1)
+		p.discard(b)
+		return false
 	}
 	b.Reset()
-	if cap(b.buf) <= p.smallLimit {
-		idx := p.bucketIndex(cap(b.buf))
-		p.observeSize(cap(b.buf), idx)
-		p.smallPool.Put(b)
+	b.resetModes()
+	if p.onReset != nil {
+		p.onReset(b)
+	}
+	return true
+}
+
+// store files a recycled buffer under the bucket matching its capacity.
+func (p *BufferPool) store(b *Buffer) {
+	s, node := p.pools()
+	if node != nil {
+		node.puts.Add(1)
+	}
+	l := s.layout
+	if p.shrinkFactor > 0 {
+		p.shrink(l, b)
+	}
+	if cap(b.buf) <= l.smallLimit {
+		idx := l.index(cap(b.buf))
+		p.observeSize(l, cap(b.buf), idx)
+		if p.budget > 0 && !p.chargeBudget(b) {
+			p.budgetDrops.Add(1)
+			p.discard(b)
+			return
+		}
+		p.retain(l, &s.small, p.smallFreelist(), smallSlot, b)
+		return
+	}
+	idx := l.index(cap(b.buf))
+	p.observeSize(l, cap(b.buf), idx)
+	if p.budget > 0 && !p.chargeBudget(b) {
+		p.budgetDrops.Add(1)
+		p.discard(b)
 		return
 	}
-	idx := p.bucketIndex(cap(b.buf))
-	p.observeSize(cap(b.buf), idx)
-	p.buckets[idx].Put(b)
+	p.retain(l, &s.buckets[idx], l.bucketFreelist(idx), idx, b)
+}
+
+// shrink reallocates b down to the size class it was last handed out for, or
+// to its bucket when that is unknown, once its capacity exceeds ShrinkFactor
+// times that size.
+func (p *BufferPool) shrink(l *bucketLayout, b *Buffer) {
+	size := b.class
+	if size <= 0 {
+		size = l.sizes[l.index(cap(b.buf))]
+	}
+	b.class = 0
+	if float64(cap(b.buf)) > p.shrinkFactor*float64(size) {
+		old := b.buf
+		b.buf = b.makeBuf(0, size)
+		b.freeBuf(old)
+		p.shrinks.Add(1)
+	}
+}
+
+// Drain discards every idle buffer the pool holds by swapping in fresh, empty
+// buckets (and emptying bounded freelists), and clears the calibration hit
+// counts. Buffers currently borrowed are unaffected and may still be Put back.
+// Cumulative counters such as Allocs, Gets, and Puts are preserved.
+func (p *BufferPool) Drain() {
+	p.reconfig.Lock()
+	defer p.reconfig.Unlock()
+	l := p.layout()
+	p.install(l)
+	for i := range l.free {
+		p.refundList(&l.free[i])
+	}
+	for i := range l.hot {
+		p.refundList(&l.hot[i])
+	}
+	for i := range l.hits {
+		l.hits[i].Store(0)
+	}
+}
+
+// install swaps in fresh, empty storage for l: the shared, per-node and
+// sharded sets, and the small freelists. Callers hold p.reconfig.
+func (p *BufferPool) install(l *bucketLayout) {
+	p.set.Store(p.newBucketSet(l))
+	for i := range p.numa {
+		p.numa[i].set.Store(p.newBucketSet(l))
+	}
+	if sh := p.sharded.Load(); sh != nil {
+		p.sharded.Store(p.newShardedSet(l, len(sh.shards)))
+	}
+	p.refundList(&p.smallFree)
+	p.refundList(&p.hotSmall)
+}
+
+// SetBucketSizes replaces the pool's bucket sizes at runtime, normalized like
+// PoolOptions.BucketSizes, so a pool can be retuned for a shifting workload
+// without being recreated. Idle pooled buffers are discarded, per-bucket
+// counters (calibration hits, SizeHistogram, per-bucket allocs and gets)
+// restart, and the default capacity moves to the new bucket that fits it,
+// as do the small-pool limit and the priority reserves unless SmallLimit or
+// PriorityReserveCap fixed them; global counters are kept. It is safe to call while Get and Put are in
+// flight: those finish against the configuration they started with, and
+// buffers they return are filed under the new buckets by their capacity.
+// It returns an error wrapping ErrInvalidConfig if no size is positive.
+func (p *BufferPool) SetBucketSizes(sizes []int) error {
+	norm := normalizeSizes(sizes)
+	if len(norm) == 0 {
+		return fmt.Errorf("%w: BucketSizes %v has no positive size", ErrInvalidConfig, sizes)
+	}
+	p.reconfig.Lock()
+	defer p.reconfig.Unlock()
+	old := p.layout()
+	p.install(p.newBucketLayout(norm))
+	p.defaultCap.Store(int64(chooseCap(norm, int(p.defaultCap.Load()))))
+	p.resizeReserves()
+	for i := range old.free {
+		p.refundList(&old.free[i])
+	}
+	for i := range old.hot {
+		p.refundList(&old.hot[i])
+	}
+	return nil
+}
+
+// oversizeClass returns the capacity allocated for a request larger than every
+// bucket.
+func oversizeClass(n int) int {
+	return nextPowerOfTwo(n)
+}
+
+// getOversized allocates a buffer for a request larger than every bucket in a
+// single step, rather than taking a top-bucket buffer only to regrow it.
+func (p *BufferPool) getOversized(n int) *Buffer {
+	p.oversizeGets.Add(1)
+	p.allocs.Add(1)
+	buf := p.newBuffer(oversizeClass(n))
+	buf.class = oversizeClass(n)
+	if p.debugLeaks {
+		p.checkout(buf)
+	}
+	p.applyGrowth(buf)
+	return buf
 }
 
 func (p *BufferPool) getSized(n int) *Buffer {
 	if n < 0 {
 		n = 0
 	}
-	if n <= p.smallLimit {
-		buf := p.smallPool.Get().(*Buffer)
-		if n > cap(buf.buf) {
-			buf.grow(n - len(buf.buf))
-		}
+	s, node := p.pools()
+	if node != nil {
+		node.gets.Add(1)
+	}
+	l := s.layout
+	idx := l.index(n)
+	l.gets[idx].Add(1)
+	if n <= l.smallLimit {
+		buf := p.take(l, &s.small, p.smallFreelist(), smallSlot)
 		if p.debugLeaks {
-			runtime.SetFinalizer(buf, func(_ *Buffer) {
-				p.leaks.Add(1)
-			})
+			p.checkout(buf)
 		}
+		p.fit(buf, n)
+		buf.class = l.smallLimit
 		return buf
 	}
-	idx := p.bucketIndex(n)
-	buf := p.buckets[idx].Get().(*Buffer)
-	// If the buffer is too small for the requested size (possible when n exceeds largest bucket),
-	// grow it to fit.
-	if n > cap(buf.buf) {
-		buf.grow(n - len(buf.buf))
+	if n > l.sizes[len(l.sizes)-1] {
+		return p.getOversized(n)
 	}
+	buf := p.take(l, &s.buckets[idx], l.bucketFreelist(idx), idx)
 	if p.debugLeaks {
-		runtime.SetFinalizer(buf, func(_ *Buffer) {
-			p.leaks.Add(1)
-		})
+		p.checkout(buf)
 	}
+	p.fit(buf, n)
+	buf.class = l.sizes[idx]
 	return buf
 }
 
-func (p *BufferPool) bucketIndex(size int) int {
-	if size <= 0 {
-		return 0
+// fit prepares a buffer taken from the pool for a request of n bytes. The
+// small pool and each bucket hold buffers of any capacity up to their size
+// (Put files a buffer under the smallest class that fits it), so buf may be
+// smaller than n. Put always Resets, but a caller that wrote to buf after Put
+// would leave it non-empty, so emptiness is restored before sizing: with len
+// 0, grow(n) guarantees cap >= n.
+func (p *BufferPool) fit(buf *Buffer, n int) {
+	p.applyGrowth(buf)
+	if len(buf.buf) != 0 {
+		buf.Reset()
 	}
-	for i, s := range p.sizes {
-		if size <= s {
-			return i
-		}
+	if n > cap(buf.buf) {
+		buf.grow(n)
+	}
+}
+
+// applyGrowth stamps the pool's configured growth policy onto buf. Pools left
+// at the default keep whatever strategy callers set on the buffer.
+func (p *BufferPool) applyGrowth(buf *Buffer) {
+	if p.growth != GrowPowerOfTwo || p.growthLimit > 0 {
+		buf.growth, buf.growthLimit = p.growth, p.growthLimit
 	}
-	return len(p.sizes) - 1
 }
 
 func chooseCap(sizes []int, target int) int {
@@ -267,29 +872,66 @@ func minInt(a, b int) int {
 	return b
 }
 
-func (p *BufferPool) observeSize(size int, bucketIdx int) {
+func (p *BufferPool) observeSize(l *bucketLayout, size int, bucketIdx int) {
 	if size <= 0 || p.observeEvery <= 0 {
 		return
 	}
-	p.bucketHits[bucketIdx].Add(1)
+	l.hits[bucketIdx].Add(1)
+	l.hist[bucketIdx].Add(1)
 	total := p.observed.Add(1)
-	if total%p.observeEvery != 0 {
+	if p.adaptive {
+		next := p.nextObserve.Load()
+		if total < next || !p.nextObserve.CompareAndSwap(next, total+p.adaptiveInterval(total)) {
+			return
+		}
+	} else if total%p.observeEvery != 0 {
 		return
 	}
-	p.recalibratePercentile()
+	p.recalibratePercentile(l)
 }
 
-func (p *BufferPool) recalibratePercentile() {
-	// Collect counts and total
+// adaptiveObserveDivisor sets the adaptive interval to total/adaptiveObserveDivisor.
+const adaptiveObserveDivisor = 8
+
+// adaptiveInterval returns how many more observations to wait before the next
+// recalibration once total observations have been seen.
+func (p *BufferPool) adaptiveInterval(total int64) int64 {
+	if iv := total / adaptiveObserveDivisor; iv > p.observeEvery {
+		return iv
+	}
+	return p.observeEvery
+}
+
+// recalibratePercentile recalibrates once the hits accumulated across
+// observation windows reach CalibrateThreshold. Hits are only cleared after a
+// calibration, so windows smaller than the threshold still add up to it.
+func (p *BufferPool) recalibratePercentile(l *bucketLayout) {
+	if !p.calibrating.CompareAndSwap(false, true) {
+		return
+	}
+	defer p.calibrating.Store(false)
 	var total int64
-	counts := make([]int64, len(p.bucketHits))
-	for i := range p.bucketHits {
-		counts[i] = p.bucketHits[i].Swap(0)
+	counts := make([]int64, len(l.hits))
+	for i := range l.hits {
+		counts[i] = l.hits[i].Load()
 		total += counts[i]
 	}
 	if total <= 0 || total < p.calibrateThr {
 		return
 	}
+	// Subtract what was read rather than zeroing, keeping hits recorded
+	// concurrently for the next calibration.
+	for i, c := range counts {
+		l.hits[i].Add(-c)
+	}
+	if i := p.percentileIndex(counts, total); i >= 0 {
+		p.setCalibratedCap(l.sizes[i], CalibratePercentile)
+	}
+}
+
+// percentileIndex returns the bucket holding the configured percentile of
+// counts, or -1 if counts is empty.
+func (p *BufferPool) percentileIndex(counts []int64, total int64) int {
 	target := int64(float64(total) * p.percentile)
 	if target <= 0 {
 		target = total
@@ -298,16 +940,188 @@ func (p *BufferPool) recalibratePercentile() {
 	for i, c := range counts {
 		cumulative += c
 		if cumulative >= target {
-			p.defaultCap.Store(int64(p.sizes[i]))
-			p.calibrations.Add(1)
-			if p.metrics != nil {
-				p.metrics(p.Stats())
-			}
+			return i
+		}
+	}
+	return -1
+}
+
+// Reasons passed to PoolOptions.OnCalibrate.
+const (
+	CalibrateManual     = "manual"     // Calibrate
+	CalibratePercentile = "percentile" // automatic percentile recalibration
+	CalibratePrime      = "prime"      // PrimeFromSamples
+)
+
+func (p *BufferPool) setCalibratedCap(size int, reason string) {
+	old := p.defaultCap.Swap(int64(size))
+	p.calibrations.Add(1)
+	if p.metrics != nil {
+		p.metrics(p.Stats())
+	}
+	if p.history != nil && int(old) != size {
+		p.history.add(CapChange{Timestamp: time.Now(), OldCap: int(old), NewCap: size})
+	}
+	if p.onCalibrate != nil {
+		p.onCalibrate(int(old), size, reason)
+	}
+}
+
+// primeBudget bounds how many buffers PrimeFromSamples preallocates in total.
+const primeBudget = 256
+
+// PrimeFromSamples feeds historical request sizes through the calibration
+// machinery, sets the default capacity to the configured percentile of the
+// samples, and preallocates up to 256 buffers split across buckets in
+// proportion to the samples (at least one per bucket that was seen). The
+// samples seed later calibrations but are not counted as Puts or in
+// SizeHistogram; the preallocations count as Allocs.
+func (p *BufferPool) PrimeFromSamples(sizes []int) {
+	s, _ := p.pools()
+	l := s.layout
+	counts := make([]int64, len(l.sizes))
+	fill := make([]int64, len(l.sizes)) // samples served by buckets rather than the small pool
+	var small, total int64
+	for _, n := range sizes {
+		if n <= 0 {
+			continue
+		}
+		idx := l.index(n)
+		l.hits[idx].Add(1) // seeds the next calibration; not a real Put
+		counts[idx]++
+		if n <= l.smallLimit {
+			small++
+		} else {
+			fill[idx]++
+		}
+		total++
+	}
+	if total == 0 {
+		return
+	}
+	if i := p.percentileIndex(counts, total); i >= 0 {
+		p.setCalibratedCap(l.sizes[i], CalibratePrime)
+	}
+
+	budget := int64(minInt(len(sizes), primeBudget))
+	share := func(c int64) int {
+		if c == 0 {
+			return 0
+		}
+		if n := c * budget / total; n > 0 {
+			return int(n)
+		}
+		return 1
+	}
+	if !p.prefillSmall(s, share(small)) {
+		return
+	}
+	for idx, c := range fill {
+		if !p.prefillBucket(s, idx, share(c)) {
+			return
+		}
+	}
+}
+
+// Prewarm allocates perBucket buffers for every bucket and the small pool
+// through their New funcs (so Allocs reflects them) and pools them, moving
+// allocation cost to startup. It does not count as Puts or affect calibration,
+// and stops once the buffers would exceed CapacityBudget.
+func (p *BufferPool) Prewarm(perBucket int) {
+	s, _ := p.pools()
+	if !p.prefillSmall(s, perBucket) {
+		return
+	}
+	for idx := range s.buckets {
+		if !p.prefillBucket(s, idx, perBucket) {
 			return
 		}
 	}
 }
 
+// PrewarmSized is like Prewarm but fills only the pool that serves size.
+func (p *BufferPool) PrewarmSized(size, count int) {
+	s, _ := p.pools()
+	if size <= s.layout.smallLimit {
+		p.prefillSmall(s, count)
+		return
+	}
+	p.prefillBucket(s, s.layout.index(size), count)
+}
+
+func (p *BufferPool) prefillSmall(s *bucketSet, count int) bool {
+	return p.prefill(s.layout, &s.small, p.smallFreelist(), smallSlot, s.layout.smallLimit, count)
+}
+
+func (p *BufferPool) prefillBucket(s *bucketSet, idx, count int) bool {
+	l := s.layout
+	return p.prefill(l, &s.buckets[idx], l.bucketFreelist(idx), idx, l.sizes[idx], count)
+}
+
+// prefill pools count buffers of size from pool's New func, charging each to
+// the CapacityBudget. It reports false, having stopped early, once the budget
+// is reached.
+func (p *BufferPool) prefill(l *bucketLayout, pool *sync.Pool, fl *freelist, slot, size, count int) bool {
+	for i := 0; i < count; i++ {
+		if p.budget > 0 && p.pooledCap.Load()+int64(size) > p.budget {
+			return false
+		}
+		b := pool.New().(*Buffer)
+		if p.budget > 0 && !p.chargeBudget(b) {
+			p.discard(b)
+			return false
+		}
+		p.retain(l, pool, fl, slot, b)
+	}
+	return true
+}
+
+// EvaluateBuckets replays the cumulative size histogram against a candidate
+// bucket configuration. Each observed Put is attributed to the capacity of the
+// bucket it landed in, so the result is relative to the current configuration:
+// wastedBytes is the extra capacity the candidate would hand out, and
+// missEstimate is the fraction of observed sizes larger than the candidate's
+// largest bucket. Candidate sizes are normalized like PoolOptions.BucketSizes.
+func (p *BufferPool) EvaluateBuckets(candidate []int) (wastedBytes int64, missEstimate float64) {
+	cand := normalizeSizes(candidate)
+	if len(cand) == 0 {
+		return 0, 0
+	}
+	l := p.layout()
+	var total, misses int64
+	for i := range l.hist {
+		c := l.hist[i].Load()
+		if c == 0 {
+			continue
+		}
+		total += c
+		size := l.sizes[i]
+		if size > cand[len(cand)-1] {
+			misses += c
+			continue
+		}
+		wastedBytes += int64(chooseCap(cand, size)-size) * c
+	}
+	if total == 0 {
+		return 0, 0
+	}
+	return wastedBytes, float64(misses) / float64(total)
+}
+
+// SizeHistogram returns, for each bucket size, the cumulative number of Puts
+// observed in that size class since the pool was created. Unlike the hit
+// counts used for calibration it is never reset, so it suits dashboards and
+// offline BucketSizes tuning (see also EvaluateBuckets). Every bucket is
+// present, including those with no observations.
+func (p *BufferPool) SizeHistogram() map[int]int64 {
+	l := p.layout()
+	out := make(map[int]int64, len(l.sizes))
+	for i, size := range l.sizes {
+		out[size] = l.hist[i].Load()
+	}
+	return out
+}
+
 // Stats provides counters for observability.
 type Stats struct {
 	Gets         int64
@@ -317,17 +1131,106 @@ type Stats struct {
 	LeakCount    int64
 	DefaultCap   int64
 	SmallLimit   int
+	UnreadPuts   int64
+	// FreelistDrops counts Puts dropped because a bounded freelist was full.
+	FreelistDrops int64
+	// OversizeDrops counts Puts dropped because the buffer exceeded MaxCap.
+	OversizeDrops int64
+	// OversizeGets counts requests larger than the biggest bucket, which are
+	// always freshly allocated (and also counted in Allocs).
+	OversizeGets int64
+	// Shrinks counts Puts that reallocated a buffer down to its bucket size.
+	Shrinks int64
+	// DoublePuts counts Puts of a buffer already returned to the pool; such
+	// Puts are ignored. Tracked only with DebugLeakDetection.
+	DoublePuts int64
+	// UseAfterPuts counts buffers found modified after Put when next handed
+	// out. Tracked only with DebugLeakDetection.
+	UseAfterPuts int64
+	// BudgetDrops counts Puts dropped because of CapacityBudget.
+	BudgetDrops int64
+	// PooledCap estimates the total capacity of idle buffers held by the
+	// pool. Tracked only with CapacityBudget.
+	PooledCap int64
+	// Buckets reports per-size-class activity, in ascending size order.
+	Buckets []BucketStat
+}
+
+// BucketStat describes one bucket of a BufferPool.
+type BucketStat struct {
+	// Size is the bucket's buffer capacity.
+	Size int
+	// Hits is the running count of Puts observed for this bucket since the
+	// last percentile calibration, which resets it.
+	Hits int64
+	// Allocs is the cumulative number of buffers allocated for this bucket.
+	// Small-pool allocations are counted only in Stats.Allocs.
+	Allocs int64
 }
 
 // Stats returns a snapshot of pool counters.
 func (p *BufferPool) Stats() Stats {
 	return Stats{
-		Gets:         p.gets.Load(),
-		Puts:         p.puts.Load(),
-		Allocs:       p.allocs.Load(),
-		Calibrations: p.calibrations.Load(),
-		LeakCount:    p.leaks.Load(),
-		DefaultCap:   p.defaultCap.Load(),
-		SmallLimit:   p.smallLimit,
+		Gets:          p.gets.Load(),
+		Puts:          p.puts.Load(),
+		Allocs:        p.allocs.Load(),
+		Calibrations:  p.calibrations.Load(),
+		LeakCount:     p.leaks.Load(),
+		DefaultCap:    p.defaultCap.Load(),
+		SmallLimit:    p.layout().smallLimit,
+		UnreadPuts:    p.unreadPuts.Load(),
+		FreelistDrops: p.freeDrops.Load(),
+		OversizeDrops: p.oversize.Load(),
+		OversizeGets:  p.oversizeGets.Load(),
+		Shrinks:       p.shrinks.Load(),
+		DoublePuts:    p.doublePuts.Load(),
+		UseAfterPuts:  p.useAfterPuts.Load(),
+		BudgetDrops:   p.budgetDrops.Load(),
+		PooledCap:     max(p.pooledCap.Load(), 0),
+		Buckets:       p.bucketStats(),
+	}
+}
+
+// ResetStats zeroes the counters reported by Stats and NodeStats (Gets, Puts,
+// Allocs, Calibrations, LeakCount, the drop and shrink counts, and per-bucket
+// allocations and gets) so a long-lived pool can be measured phase by phase. The default
+// capacity, bucket sizes and calibration state are left intact. It is not
+// synchronized with in-flight Get/Put calls, which may land on either side of
+// the reset; counts simply restart from a near-zero baseline.
+func (p *BufferPool) ResetStats() {
+	p.gets.Store(0)
+	p.puts.Store(0)
+	p.allocs.Store(0)
+	p.calibrations.Store(0)
+	p.leaks.Store(0)
+	p.unreadPuts.Store(0)
+	p.freeDrops.Store(0)
+	p.oversize.Store(0)
+	p.oversizeGets.Store(0)
+	p.shrinks.Store(0)
+	p.doublePuts.Store(0)
+	p.useAfterPuts.Store(0)
+	p.budgetDrops.Store(0)
+	l := p.layout()
+	for i := range l.allocs {
+		l.allocs[i].Store(0)
+		l.gets[i].Store(0)
+	}
+	for i := range p.numa {
+		p.numa[i].gets.Store(0)
+		p.numa[i].puts.Store(0)
+	}
+}
+
+func (p *BufferPool) bucketStats() []BucketStat {
+	l := p.layout()
+	out := make([]BucketStat, len(l.sizes))
+	for i, size := range l.sizes {
+		out[i] = BucketStat{
+			Size:   size,
+			Hits:   l.hits[i].Load(),
+			Allocs: l.allocs[i].Load(),
+		}
 	}
+	return out
 }
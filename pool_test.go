@@ -1,8 +1,14 @@
 package gobuff
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestBufferBasicWriteRead(t *testing.T) {
@@ -104,3 +110,1178 @@ func TestBufferPoolStress(t *testing.T) {
 		t.Fatalf("unexpected leaks reported: %d", leaks)
 	}
 }
+
+func TestBufferPoolEvaluateBuckets(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes: []int{64, 128, 256},
+		SmallLimit:  64,
+	})
+	for i := 0; i < 30; i++ {
+		p.Put(NewBuffer(64))
+	}
+	for i := 0; i < 10; i++ {
+		p.Put(NewBuffer(256))
+	}
+
+	wasted, miss := p.EvaluateBuckets([]int{64, 256})
+	if wasted != 0 || miss != 0 {
+		t.Fatalf("exact candidate: wasted=%d miss=%v", wasted, miss)
+	}
+	wasted, miss = p.EvaluateBuckets([]int{128})
+	if wasted != 30*64 {
+		t.Fatalf("expected wasted=%d, got %d", 30*64, wasted)
+	}
+	if miss != 0.25 {
+		t.Fatalf("expected miss=0.25, got %v", miss)
+	}
+}
+
+func TestBufferPoolForEachPooledWipes(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:      []int{64, 256},
+		SmallLimit:       64,
+		BoundedFreelists: true,
+	})
+	var held []*Buffer
+	for i := 0; i < 4; i++ {
+		b := p.GetSized(200)
+		_, _ = b.WriteString("secret-token")
+		held = append(held, b)
+	}
+	for _, b := range held {
+		p.Put(b)
+	}
+
+	visited := 0
+	p.ForEachPooled(func(b *Buffer) {
+		visited++
+		clear(b.UnsafeBytes()[:b.Cap()])
+	})
+	if visited != len(held) {
+		t.Fatalf("expected to visit %d buffers, got %d", len(held), visited)
+	}
+
+	allocs := p.Stats().Allocs
+	for range held {
+		b := p.GetSized(200)
+		for i, v := range b.UnsafeBytes()[:b.Cap()] {
+			if v != 0 {
+				t.Fatalf("byte %d not wiped: %q", i, v)
+			}
+		}
+	}
+	if got := p.Stats().Allocs; got != allocs {
+		t.Fatalf("expected Gets to reuse pooled buffers, allocs %d -> %d", allocs, got)
+	}
+}
+
+func TestNewBufferPoolForWaste(t *testing.T) {
+	const maxWaste = 0.25
+	p := NewBufferPoolForWaste(64, 65536, maxWaste)
+	sizes := p.layout().sizes
+	if sizes[0] != 64 || sizes[len(sizes)-1] != 65536 {
+		t.Fatalf("unexpected bucket range: %v", sizes)
+	}
+	for i := 1; i < len(sizes); i++ {
+		// Worst case: a request one byte above the previous bucket.
+		smallest := sizes[i-1] + 1
+		waste := float64(sizes[i]-smallest) / float64(sizes[i])
+		if waste > maxWaste {
+			t.Fatalf("bucket %d wastes %.2f > %.2f: %v", sizes[i], waste, maxWaste, sizes)
+		}
+	}
+
+	b := p.GetSized(1000)
+	if b.Cap() < 1000 || b.Cap() > 1000*4/3+1 {
+		t.Fatalf("unexpected capacity %d for 1000-byte request", b.Cap())
+	}
+	_, _ = b.WriteString("ok")
+	p.Put(b)
+}
+
+func TestBufferPoolWarnUnreadOnPut(t *testing.T) {
+	quiet := NewBufferPool(0)
+	b := quiet.Get()
+	_, _ = b.WriteString("pending")
+	quiet.Put(b)
+	if got := quiet.Stats().UnreadPuts; got != 0 {
+		t.Fatalf("expected no warnings by default, got %d", got)
+	}
+
+	var discarded int
+	p := NewBufferPoolWithOptions(PoolOptions{
+		DebugWarnUnreadOnPut: true,
+		OnUnreadPut:          func(unread int) { discarded += unread },
+	})
+	b = p.Get()
+	_, _ = b.WriteString("pending")
+	p.Put(b)
+
+	drained := p.Get()
+	_, _ = drained.WriteString("x")
+	_, _ = drained.ReadByte()
+	p.Put(drained)
+
+	if got := p.Stats().UnreadPuts; got != 1 {
+		t.Fatalf("expected 1 unread put, got %d", got)
+	}
+	if discarded != len("pending") {
+		t.Fatalf("expected callback with %d bytes, got %d", len("pending"), discarded)
+	}
+}
+
+func TestBufferPoolWithDefaultCap(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes: []int{64, 1024, 8192},
+		InitialCap:  64,
+	})
+	p.WithDefaultCap(5000, func() {
+		b := p.Get()
+		if b.Cap() != 8192 {
+			t.Fatalf("expected overridden cap 8192, got %d", b.Cap())
+		}
+	})
+	if b := p.Get(); b.Cap() != 64 {
+		t.Fatalf("expected original cap 64, got %d", b.Cap())
+	}
+
+	func() {
+		defer func() { _ = recover() }()
+		p.WithDefaultCap(1000, func() { panic("boom") })
+	}()
+	if got := p.Stats().DefaultCap; got != 64 {
+		t.Fatalf("expected default cap restored after panic, got %d", got)
+	}
+}
+
+func TestBufferPoolMaxPerBucket(t *testing.T) {
+	var evicted int
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:      []int{64, 256},
+		SmallLimit:       64,
+		BoundedFreelists: true,
+		MaxPerBucket:     3,
+		OnEvict:          func(*Buffer) { evicted++ },
+	})
+	for i := 0; i < 5; i++ {
+		p.Put(NewBuffer(256))
+	}
+	if got := len(p.layout().free[1].bufs); got != 3 {
+		t.Fatalf("expected freelist capped at 3, got %d", got)
+	}
+	if got := p.Stats().FreelistDrops; got != 2 {
+		t.Fatalf("expected 2 drops, got %d", got)
+	}
+	if evicted != 2 {
+		t.Fatalf("expected OnEvict for 2 buffers, got %d", evicted)
+	}
+}
+
+func TestBufferPoolPrimeFromSamples(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:      []int{64, 256, 1024, 4096},
+		SmallLimit:       64,
+		BoundedFreelists: true,
+		Percentile:       0.9,
+	})
+	var samples []int
+	for i := 0; i < 80; i++ {
+		samples = append(samples, 200) // 256 bucket
+	}
+	for i := 0; i < 15; i++ {
+		samples = append(samples, 900) // 1024 bucket
+	}
+	for i := 0; i < 5; i++ {
+		samples = append(samples, 3000) // 4096 bucket
+	}
+	p.PrimeFromSamples(samples)
+
+	if got := p.Stats().DefaultCap; got != 1024 {
+		t.Fatalf("expected p90 default cap 1024, got %d", got)
+	}
+	want := []int{0, 80, 15, 5}
+	for i, w := range want {
+		if got := len(p.layout().free[i].bufs); got != w {
+			t.Fatalf("bucket %d: expected %d prefilled buffers, got %d", p.layout().sizes[i], w, got)
+		}
+	}
+	st := p.Stats()
+	if st.Allocs != 100 {
+		t.Fatalf("expected 100 allocations for prefill, got %d", st.Allocs)
+	}
+	if st.Gets != 0 || st.Puts != 0 {
+		t.Fatalf("priming counted as traffic: Gets=%d Puts=%d", st.Gets, st.Puts)
+	}
+	for _, c := range p.SizeHistogram() {
+		if c != 0 {
+			t.Fatalf("priming inflated SizeHistogram: %+v", p.SizeHistogram())
+		}
+	}
+}
+
+func TestBufferPoolBorrowContext(t *testing.T) {
+	p := NewBufferPool(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	buf, release := p.BorrowContext(ctx, 100)
+	if buf.Cap() < 100 {
+		t.Fatalf("expected cap >= 100, got %d", buf.Cap())
+	}
+	cancel()
+	for i := 0; i < 100 && p.Stats().Puts == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if got := p.Stats().Puts; got != 1 {
+		t.Fatalf("expected cancellation to return the buffer, puts=%d", got)
+	}
+	release()
+	if got := p.Stats().Puts; got != 1 {
+		t.Fatalf("expected release after auto-return to be a no-op, puts=%d", got)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	_, release = p.BorrowContext(ctx, 100)
+	release()
+	release()
+	cancel()
+	time.Sleep(time.Millisecond)
+	if s := p.Stats(); s.Gets != 2 || s.Puts != 2 {
+		t.Fatalf("expected exactly one Put per borrow, got %+v", s)
+	}
+}
+
+func TestBufferPoolBorrowTraced(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{BucketSizes: []int{64, 256, 1024}, SmallLimit: 32})
+
+	small, l1 := p.BorrowTraced(10)
+	mid, l2 := p.BorrowTraced(200)
+	big, l3 := p.BorrowTraced(900)
+	huge, l4 := p.BorrowTraced(3000)
+	if l1.ID == l2.ID || l2.ID == l3.ID || l1.ID == l3.ID {
+		t.Fatalf("expected distinct lease IDs, got %d %d %d", l1.ID, l2.ID, l3.ID)
+	}
+	for _, c := range []struct {
+		buf   *Buffer
+		lease Lease
+		want  int
+	}{{small, l1, 32}, {mid, l2, 256}, {big, l3, 1024}, {huge, l4, 4096}} {
+		if c.lease.BucketSize != c.want || c.buf.Cap() < c.want {
+			t.Fatalf("lease %d: expected bucket %d, got %d (cap %d)", c.lease.ID, c.want, c.lease.BucketSize, c.buf.Cap())
+		}
+	}
+
+	l1.Release()
+	l2.Release()
+	l3.Release()
+	l4.Release()
+	copied := l3
+	copied.Release()
+	if s := p.Stats(); s.Gets != 4 || s.Puts != 4 {
+		t.Fatalf("expected each lease to return its buffer once, got %+v", s)
+	}
+}
+
+func TestBufferPoolSplitHeaderBody(t *testing.T) {
+	p := NewBufferPool(0)
+	src := NewBuffer(0)
+	_, _ = src.WriteString("HDR1payload bytes")
+
+	header, body := p.SplitHeaderBody(src, 4)
+	if string(header) != "HDR1" {
+		t.Fatalf("unexpected header: %q", header)
+	}
+	if got := body.String(); got != "payload bytes" {
+		t.Fatalf("unexpected body: %q", got)
+	}
+	if src.Len() != 0 {
+		t.Fatalf("expected src to be consumed, len=%d", src.Len())
+	}
+	p.Put(body)
+	if string(header) != "HDR1" {
+		t.Fatalf("header view changed after Put(body): %q", header)
+	}
+	if s := p.Stats(); s.Gets != 1 || s.Puts != 1 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+}
+
+func TestBufferDrainToSurvivesPut(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{BoundedFreelists: true})
+	buf := p.Get()
+	_, _ = buf.WriteString("xxowned")
+	_ = buf.Next(2)
+
+	data := buf.DrainTo()
+	if string(data) != "owned" || buf.Len() != 0 {
+		t.Fatalf("DrainTo = %q, remaining len %d", data, buf.Len())
+	}
+	p.Put(buf)
+	reused := p.Get()
+	_, _ = reused.WriteString("XXXXXXX")
+	if string(data) != "owned" {
+		t.Fatalf("drained bytes alias pooled storage: %q", data)
+	}
+	if got := NewBuffer(0).DrainTo(); len(got) != 0 {
+		t.Fatalf("expected empty result for empty buffer")
+	}
+}
+
+func TestBufferPoolFragment(t *testing.T) {
+	p := NewBufferPool(0)
+	data := bytes.Repeat([]byte("0123456789"), 25) // 250 bytes
+	src := NewBuffer(0)
+	_, _ = src.Write(data)
+
+	frags := p.Fragment(src, 64)
+	if len(frags) != 4 {
+		t.Fatalf("expected 4 fragments, got %d", len(frags))
+	}
+	if src.Len() != 0 {
+		t.Fatalf("expected src to be drained, len=%d", src.Len())
+	}
+	var joined []byte
+	for i, f := range frags {
+		want := 64
+		if i == len(frags)-1 {
+			want = 250 - 3*64
+		}
+		if f.Len() != want {
+			t.Fatalf("fragment %d: expected %d bytes, got %d", i, want, f.Len())
+		}
+		joined = append(joined, f.Bytes()...)
+	}
+	if !bytes.Equal(joined, data) {
+		t.Fatalf("fragments do not reassemble to the source")
+	}
+	for _, f := range frags {
+		p.Put(f)
+	}
+	if s := p.Stats(); s.Gets != 4 || s.Puts != 4 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+	if p.Fragment(src, 64) != nil || p.Fragment(NewBuffer(0), 0) != nil {
+		t.Fatalf("expected nil for empty source or invalid chunk size")
+	}
+}
+
+func TestBufferPoolOnReset(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BoundedFreelists: true,
+		OnReset: func(b *Buffer) {
+			b.TrackFields(false)
+			b.SetGrowthStrategy(GrowPowerOfTwo)
+		},
+	})
+	b := p.Get()
+	b.TrackFields(true)
+	b.SetGrowthStrategy(GrowExact)
+	b.MarkField("tag")
+	p.Put(b)
+
+	again := p.Get()
+	if again != b {
+		t.Fatalf("expected the same buffer from the freelist")
+	}
+	if again.FieldOffsets() != nil || again.growth != GrowPowerOfTwo {
+		t.Fatalf("expected OnReset to clear custom state")
+	}
+}
+
+func TestBufferPoolGrowthStrategy(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:     []int{64, 1024},
+		GrowthStrategy:  GrowDampened,
+		GrowthThreshold: 4096,
+	})
+	b := p.GetSized(1024)
+	_, _ = b.ReadFrom(bytes.NewReader(make([]byte, 5000)))
+	if c := b.Cap(); c >= 8192 {
+		t.Fatalf("expected dampened growth past the threshold, got cap %d", c)
+	}
+	p.Put(b)
+
+	def := NewBufferPoolWithOptions(PoolOptions{BucketSizes: []int{64, 1024}})
+	b = def.GetSized(1024)
+	_, _ = b.ReadFrom(bytes.NewReader(make([]byte, 5000)))
+	if c := b.Cap(); c != 8192 {
+		t.Fatalf("expected default power-of-two growth, got cap %d", c)
+	}
+}
+
+func TestBufferPoolShrinkFactor(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:      []int{64, 1024},
+		ShrinkFactor:     4,
+		BoundedFreelists: true,
+	})
+	b := p.GetSized(1024)
+	_, _ = b.Write(make([]byte, 16*1024))
+	p.Put(b)
+	if s := p.Stats(); s.Shrinks != 1 || s.OversizeDrops != 0 {
+		t.Fatalf("expected one shrink and no drops, got %+v", s)
+	}
+	again := p.GetSized(1024)
+	if again != b || again.Cap() != 1024 {
+		t.Fatalf("expected the same buffer shrunk to 1024, got cap %d", again.Cap())
+	}
+
+	// Within the factor the backing array is kept as is.
+	_, _ = again.Write(make([]byte, 3*1024))
+	grown := again.Cap()
+	p.Put(again)
+	if c := p.GetSized(1024).Cap(); c != grown {
+		t.Fatalf("expected cap %d to be kept within ShrinkFactor, got %d", grown, c)
+	}
+	if s := p.Stats(); s.Shrinks != 1 {
+		t.Fatalf("expected no further shrinks, got %d", s.Shrinks)
+	}
+}
+
+func TestBufferPoolShrinkFactorSmallBucket(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:      []int{64, 256, 1024, 4096},
+		ShrinkFactor:     2,
+		BoundedFreelists: true,
+	})
+	b := p.GetSized(200)
+	_, _ = b.Write(make([]byte, 1000)) // ballooned into the 1024 bucket
+	p.Put(b)
+	if s := p.Stats(); s.Shrinks != 1 {
+		t.Fatalf("expected the ballooned buffer to shrink, got %d shrinks", s.Shrinks)
+	}
+	if again := p.GetSized(200); again != b || again.Cap() != 256 {
+		t.Fatalf("expected the same buffer shrunk to 256, got cap %d", again.Cap())
+	}
+}
+
+func TestBufferPoolBucketStats(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:      []int{64, 256, 1024},
+		BoundedFreelists: true,
+	})
+	held := []*Buffer{p.GetSized(200), p.GetSized(200), p.GetSized(900)}
+	for _, b := range held {
+		p.Put(b)
+	}
+	_ = p.GetSized(200)
+
+	s := p.Stats()
+	if len(s.Buckets) != 3 {
+		t.Fatalf("expected 3 bucket stats, got %d", len(s.Buckets))
+	}
+	want := []BucketStat{
+		{Size: 64},
+		{Size: 256, Hits: 2, Allocs: 2},
+		{Size: 1024, Hits: 1, Allocs: 1},
+	}
+	for i, w := range want {
+		if s.Buckets[i] != w {
+			t.Fatalf("bucket %d: expected %+v, got %+v", i, w, s.Buckets[i])
+		}
+	}
+	if s.Buckets[1].Hits != p.Stats().Buckets[1].Hits {
+		t.Fatalf("Stats should not reset hit counters")
+	}
+}
+
+func TestBufferPoolResetStats(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{BucketSizes: []int{64, 1024}, BoundedFreelists: true})
+	for i := 0; i < 3; i++ {
+		p.Put(p.GetSized(512))
+	}
+	p.Calibrate(1000)
+	before := p.Stats()
+	if before.Gets != 3 || before.Allocs != 1 || before.Calibrations != 1 {
+		t.Fatalf("unexpected stats before reset: %+v", before)
+	}
+
+	p.ResetStats()
+	s := p.Stats()
+	if s.Gets != 0 || s.Puts != 0 || s.Allocs != 0 || s.Calibrations != 0 || s.LeakCount != 0 {
+		t.Fatalf("expected counters to be zeroed, got %+v", s)
+	}
+	if s.Buckets[1].Allocs != 0 {
+		t.Fatalf("expected per-bucket allocs to be zeroed, got %d", s.Buckets[1].Allocs)
+	}
+	if s.DefaultCap != before.DefaultCap || s.SmallLimit != before.SmallLimit {
+		t.Fatalf("expected sizing to be preserved: before %+v after %+v", before, s)
+	}
+
+	p.Put(p.GetSized(512))
+	if s := p.Stats(); s.Gets != 1 || s.Puts != 1 || s.Allocs != 0 {
+		t.Fatalf("expected counts to restart from zero, got %+v", s)
+	}
+}
+
+func TestNewBufferPoolWithOptionsErrStrict(t *testing.T) {
+	bad := []PoolOptions{
+		{BucketSizes: []int{0, 0, -1}, StrictConfig: true},
+		{Percentile: 1.5, StrictConfig: true},
+		{Percentile: -0.1, StrictConfig: true},
+		{CalibrateThreshold: -1, StrictConfig: true},
+	}
+	for _, opts := range bad {
+		p, err := NewBufferPoolWithOptionsErr(opts)
+		if !errors.Is(err, ErrInvalidConfig) || p != nil {
+			t.Fatalf("expected ErrInvalidConfig for %+v, got pool=%v err=%v", opts, p, err)
+		}
+	}
+
+	p, err := NewBufferPoolWithOptionsErr(PoolOptions{BucketSizes: []int{0, 64}, Percentile: 0.9, StrictConfig: true})
+	if err != nil || p == nil {
+		t.Fatalf("expected valid strict config to succeed, got %v", err)
+	}
+	lenient, err := NewBufferPoolWithOptionsErr(PoolOptions{BucketSizes: []int{0, 0}})
+	if err != nil || lenient.Stats().DefaultCap != int64(defaultBucketSizes[0]) {
+		t.Fatalf("expected non-strict config to fall back to defaults, got err=%v", err)
+	}
+}
+
+func TestBufferPoolOnCalibrate(t *testing.T) {
+	type event struct {
+		old, new int
+		reason   string
+	}
+	var events []event
+	var metrics int
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:        []int{64, 256, 1024},
+		InitialCap:         64,
+		ObserveEvery:       4,
+		CalibrateThreshold: 4,
+		BoundedFreelists:   true,
+		Metrics:            func(Stats) { metrics++ },
+		OnCalibrate: func(old, new int, reason string) {
+			events = append(events, event{old, new, reason})
+		},
+	})
+
+	p.Calibrate(200)
+	for i := 0; i < 4; i++ {
+		p.Put(p.GetSized(1000))
+	}
+
+	want := []event{
+		{64, 256, CalibrateManual},
+		{256, 1024, CalibratePercentile},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("event %d: expected %+v, got %+v", i, want[i], events[i])
+		}
+	}
+	if metrics != 2 {
+		t.Fatalf("expected Metrics to still fire on each calibration, got %d", metrics)
+	}
+}
+
+func TestBufferPoolMaxCap(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:      []int{64, 1024},
+		MaxCap:           4096,
+		BoundedFreelists: true,
+	})
+	big := p.GetSized(64)
+	_, _ = big.ReadFrom(bytes.NewReader(make([]byte, 1<<20)))
+	p.Put(big)
+	if got := p.Stats().OversizeDrops; got != 1 {
+		t.Fatalf("expected 1 oversize drop, got %d", got)
+	}
+	if n := len(p.layout().free[1].bufs) + len(p.smallFree.bufs); n != 0 {
+		t.Fatalf("oversized buffer was retained")
+	}
+
+	ok := p.GetSized(1000)
+	p.Put(ok)
+	if got := p.Stats().OversizeDrops; got != 1 {
+		t.Fatalf("in-range buffer was dropped: %d", got)
+	}
+}
+
+func TestBufferPoolDrain(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes: []int{64, 256},
+		SmallLimit:  64,
+	})
+	borrowed := p.GetSized(200)
+	for i := 0; i < 4; i++ {
+		p.Put(p.GetSized(200))
+	}
+	allocs := p.Stats().Allocs
+
+	p.Drain()
+	hits := p.layout().hits
+	for i := range hits {
+		if hits[i].Load() != 0 {
+			t.Fatalf("bucket %d hits not reset", i)
+		}
+	}
+	if p.Stats().Allocs != allocs {
+		t.Fatalf("Drain changed Allocs")
+	}
+	b := p.GetSized(200)
+	if got := p.Stats().Allocs; got != allocs+1 {
+		t.Fatalf("expected a fresh allocation after Drain, allocs %d -> %d", allocs, got)
+	}
+	p.Put(b)
+	p.Put(borrowed) // in-flight buffers can still be returned
+}
+
+func TestBufferPoolDrainBounded(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{BoundedFreelists: true})
+	for i := 0; i < 3; i++ {
+		p.Put(NewBuffer(1024))
+	}
+	p.Drain()
+	n := 0
+	p.ForEachPooled(func(*Buffer) { n++ })
+	if n != 0 {
+		t.Fatalf("expected empty freelists after Drain, found %d buffers", n)
+	}
+}
+
+func TestBufferPoolPrewarm(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:      []int{64, 256, 1024},
+		SmallLimit:       64,
+		BoundedFreelists: true,
+	})
+	p.Prewarm(2)
+	if got := p.Stats().Allocs; got != 2*4 {
+		t.Fatalf("expected 8 allocations (3 buckets + small pool), got %d", got)
+	}
+	if s := p.Stats(); s.Puts != 0 {
+		t.Fatalf("prewarm should not count as Puts: %+v", s)
+	}
+	for i := 0; i < 2; i++ {
+		b := p.GetSized(1000)
+		if b.Cap() != 1024 {
+			t.Fatalf("unexpected cap %d", b.Cap())
+		}
+	}
+	if got := p.Stats().Allocs; got != 8 {
+		t.Fatalf("Gets after Prewarm allocated: %d", got)
+	}
+
+	p.PrewarmSized(200, 3)
+	if got := len(p.layout().free[1].bufs); got != 2+3 {
+		t.Fatalf("expected 5 buffers in the 256 bucket, got %d", got)
+	}
+	p.PrewarmSized(10, 1)
+	if got := len(p.smallFree.bufs); got != 3 {
+		t.Fatalf("expected 3 small buffers, got %d", got)
+	}
+}
+
+func TestBufferPoolAdaptiveObserve(t *testing.T) {
+	newPool := func(adaptive bool) *BufferPool {
+		return NewBufferPoolWithOptions(PoolOptions{
+			BucketSizes:        []int{64, 256},
+			ObserveEvery:       100,
+			CalibrateThreshold: 1,
+			AdaptiveObserve:    adaptive,
+		})
+	}
+	// calibrationsAt feeds observations up to each checkpoint and records how
+	// many recalibrations have run by then.
+	calibrationsAt := func(p *BufferPool, checkpoints []int) []int64 {
+		var out []int64
+		seen := 0
+		for _, c := range checkpoints {
+			for ; seen < c; seen++ {
+				p.observeSize(p.layout(), 256, 1)
+			}
+			out = append(out, p.Stats().Calibrations)
+		}
+		return out
+	}
+	checkpoints := []int{1000, 10000, 100000}
+
+	fixed := calibrationsAt(newPool(false), checkpoints)
+	if fixed[2]-fixed[1] != 900 {
+		t.Fatalf("expected linear recalibration without adaptation, got %v", fixed)
+	}
+
+	adaptive := calibrationsAt(newPool(true), checkpoints)
+	first, second := adaptive[1]-adaptive[0], adaptive[2]-adaptive[1]
+	if second > first+2 || second < first-2 {
+		t.Fatalf("expected a stable count per decade, got %d then %d (%v)", first, second, adaptive)
+	}
+	if second > 30 {
+		t.Fatalf("adaptive mode recalibrated too often: %v", adaptive)
+	}
+}
+
+func TestBufferPoolShardedConcurrent(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes: []int{64, 256, 1024},
+		SmallLimit:  64,
+		Sharded:     true,
+	})
+	const workers = 16
+	const iters = 512
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < iters; j++ {
+				size := (j * (id + 1)) % 1200
+				b := p.GetSized(size)
+				if b.Cap() < size {
+					t.Errorf("cap %d < requested %d", b.Cap(), size)
+					return
+				}
+				_ = b.WriteByte(byte(j))
+				p.Put(b)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	s := p.Stats()
+	if s.Gets != workers*iters || s.Puts != workers*iters {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+	if s.Allocs >= s.Gets {
+		t.Fatalf("expected sharded buckets to reuse buffers: allocs=%d gets=%d", s.Allocs, s.Gets)
+	}
+}
+
+func TestShardedSetLocalReuse(t *testing.T) {
+	p := NewBufferPool(1024)
+	s := p.newShardedSet(p.layout(), 8)
+	const iters = 1000
+	same := 0
+	for i := 0; i < iters; i++ {
+		b := p.newBuffer(64)
+		s.put(0, b)
+		if s.get(0) == b {
+			same++
+		}
+	}
+	// sync.Pool drops some Puts under the race detector, so allow slack.
+	if same < iters/2 {
+		t.Fatalf("Get after Put on one goroutine should hit the same shard: %d/%d", same, iters)
+	}
+}
+
+func TestBufferPoolHotReserveSurvivesGC(t *testing.T) {
+	const hot = 4
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes: []int{64, 1024},
+		HotReserve:  hot,
+	})
+	const n = hot + 2
+	bufs := make([]*Buffer, n)
+	for i := range bufs {
+		bufs[i] = p.GetSized(1000)
+	}
+	for _, b := range bufs {
+		p.Put(b)
+	}
+	allocs := p.Stats().Allocs
+
+	// Two cycles clear both sync.Pool generations.
+	runtime.GC()
+	runtime.GC()
+
+	for i := range bufs {
+		bufs[i] = p.GetSized(1000)
+	}
+	if got := p.Stats().Allocs - allocs; got != n-hot {
+		t.Fatalf("expected only %d reallocations beyond the hot reserve, got %d", n-hot, got)
+	}
+}
+
+func TestBufferPoolCapacityBudget(t *testing.T) {
+	pool := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:      []int{512},
+		SmallLimit:       64,
+		BoundedFreelists: true,
+		CapacityBudget:   1024,
+	})
+	bufs := []*Buffer{pool.GetSized(500), pool.GetSized(500), pool.GetSized(500)}
+	for _, b := range bufs {
+		pool.Put(b)
+	}
+	st := pool.Stats()
+	if st.PooledCap != 1024 || st.BudgetDrops != 1 {
+		t.Fatalf("PooledCap=%d BudgetDrops=%d, want 1024 and 1", st.PooledCap, st.BudgetDrops)
+	}
+	b := pool.GetSized(500)
+	if got := pool.Stats().PooledCap; got != 512 {
+		t.Fatalf("PooledCap after Get = %d, want 512", got)
+	}
+	pool.Put(b)
+	pool.Drain()
+	if got := pool.Stats().PooledCap; got != 0 {
+		t.Fatalf("PooledCap after Drain = %d, want 0", got)
+	}
+}
+
+func TestBufferPoolCapacityBudgetAfterGC(t *testing.T) {
+	pool := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:    []int{512},
+		SmallLimit:     64,
+		CapacityBudget: 1024,
+	})
+	putTwo := func() {
+		a, b := pool.GetSized(500), pool.GetSized(500)
+		pool.Put(a)
+		pool.Put(b)
+	}
+	putTwo()
+	// sync.Pool frees idle buffers over two GC cycles; the finalizers that
+	// refund them run afterwards on their own goroutine.
+	for i := 0; i < 100 && pool.Stats().PooledCap > 0; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	if got := pool.Stats().PooledCap; got != 0 {
+		t.Fatalf("PooledCap after GC = %d, want collected buffers refunded", got)
+	}
+	drops := pool.Stats().BudgetDrops
+	putTwo()
+	if st := pool.Stats(); st.BudgetDrops != drops {
+		t.Fatalf("Puts after GC were dropped: BudgetDrops %d -> %d", drops, st.BudgetDrops)
+	}
+}
+
+func TestBufferPoolPrewarmRespectsBudget(t *testing.T) {
+	pool := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:      []int{512, 1024},
+		SmallLimit:       64,
+		BoundedFreelists: true,
+		CapacityBudget:   2048,
+	})
+	pool.Prewarm(4)
+	st := pool.Stats()
+	if st.PooledCap > 2048 || st.PooledCap < 2048-512 {
+		t.Fatalf("PooledCap after Prewarm = %d, want at most 2048", st.PooledCap)
+	}
+	if st.Allocs != 7 {
+		t.Fatalf("Allocs = %d, want Prewarm to stop allocating at the budget", st.Allocs)
+	}
+	pool.Drain()
+	pool.PrimeFromSamples([]int{600, 600, 600, 600, 600, 600})
+	if got := pool.Stats().PooledCap; got > 2048 {
+		t.Fatalf("PooledCap after PrimeFromSamples = %d, want at most 2048", got)
+	}
+}
+
+func TestBufferPoolCalibrationAccumulatesWindows(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:        []int{64, 256, 1024},
+		SmallLimit:         32,
+		ObserveEvery:       16,
+		CalibrateThreshold: 200,
+	})
+	for i := 0; i < 199; i++ {
+		p.Put(p.GetSized(1000))
+	}
+	if got := p.Stats().Calibrations; got != 0 {
+		t.Fatalf("calibrated before the threshold: %d", got)
+	}
+	for i := 0; i < 17; i++ {
+		p.Put(p.GetSized(1000))
+	}
+	st := p.Stats()
+	if st.Calibrations != 1 || st.DefaultCap != 1024 {
+		t.Fatalf("Calibrations=%d DefaultCap=%d, want 1 and 1024", st.Calibrations, st.DefaultCap)
+	}
+	for _, b := range st.Buckets {
+		if b.Hits > 16 {
+			t.Fatalf("hits not cleared after calibration: %+v", st.Buckets)
+		}
+	}
+}
+
+func TestBufferPoolOversizeGets(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{BucketSizes: []int{64, 256}, SmallLimit: 32, BoundedFreelists: true})
+	p.Put(p.GetSized(200))
+	allocs := p.Stats().Allocs
+	b := p.GetSized(1000)
+	if b.Cap() != 1024 || b.Len() != 0 {
+		t.Fatalf("oversized buffer cap=%d len=%d, want 1024 and 0", b.Cap(), b.Len())
+	}
+	st := p.Stats()
+	if st.OversizeGets != 1 || st.Allocs != allocs+1 {
+		t.Fatalf("OversizeGets=%d Allocs=%d, want 1 and %d", st.OversizeGets, st.Allocs, allocs+1)
+	}
+	p.GetSized(200)
+	if got := p.Stats().Allocs; got != allocs+1 {
+		t.Fatalf("oversized Get consumed the top bucket's buffer: Allocs=%d", got)
+	}
+}
+
+func TestBufferPoolSmallGetsFit(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:      []int{64, 256},
+		SmallLimit:       64,
+		BoundedFreelists: true,
+	})
+	for n := 1; n <= 64; n++ {
+		for _, c := range []int{1, 7, 16, 33, 64} {
+			p.Put(NewBuffer(c))
+		}
+		for i := 0; i < 5; i++ {
+			b := p.GetSized(n)
+			if b.Cap() < n || b.Len() != 0 {
+				t.Fatalf("GetSized(%d) returned cap=%d len=%d", n, b.Cap(), b.Len())
+			}
+		}
+	}
+
+	// A buffer written to after Put must still come back empty and big enough.
+	b := p.GetSized(8)
+	p.Put(b)
+	_, _ = b.WriteString("stale")
+	got := p.GetSized(48)
+	if got.Len() != 0 || got.Cap() < 48 {
+		t.Fatalf("reused buffer len=%d cap=%d, want empty with cap >= 48", got.Len(), got.Cap())
+	}
+}
+
+func TestBufferPoolSizeHistogram(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:        []int{64, 256, 1024},
+		SmallLimit:         32,
+		ObserveEvery:       4,
+		CalibrateThreshold: 4,
+	})
+	for i := 0; i < 5; i++ {
+		p.Put(p.GetSized(200))
+	}
+	for i := 0; i < 3; i++ {
+		p.Put(p.GetSized(1000))
+	}
+	want := map[int]int64{64: 0, 256: 5, 1024: 3}
+	if got := p.SizeHistogram(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("SizeHistogram = %v, want %v", got, want)
+	}
+	if p.Stats().Calibrations == 0 {
+		t.Fatalf("expected a calibration to have cleared the hit counts")
+	}
+}
+
+func TestBufferPoolMetricsSnapshot(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:      []int{64, 256},
+		SmallLimit:       32,
+		BoundedFreelists: true,
+	})
+	p.Put(p.GetSized(10))
+	p.Put(p.GetSized(200))
+	p.Put(p.GetSized(200))
+	m := p.MetricsSnapshot()
+	if m.Gets != 3 || m.Puts != 3 || m.Allocs != 2 {
+		t.Fatalf("global counters = %+v", m)
+	}
+	want := []BucketMetrics{
+		{Size: 64, Gets: 1, Puts: 1, Allocs: 0},
+		{Size: 256, Gets: 2, Puts: 2, Allocs: 1},
+	}
+	if !reflect.DeepEqual(m.Buckets, want) {
+		t.Fatalf("Buckets = %+v, want %+v", m.Buckets, want)
+	}
+}
+
+func TestBufferPoolSetBucketSizes(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{BucketSizes: []int{64, 256}, InitialCap: 200})
+	if err := p.SetBucketSizes([]int{0, -1}); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig, got %v", err)
+	}
+	if err := p.SetBucketSizes([]int{4096, 1024, 1024}); err != nil {
+		t.Fatal(err)
+	}
+	st := p.Stats()
+	if st.DefaultCap != 1024 || len(st.Buckets) != 2 || st.Buckets[1].Size != 4096 {
+		t.Fatalf("after reconfiguration DefaultCap=%d Buckets=%+v", st.DefaultCap, st.Buckets)
+	}
+	if b := p.GetSized(3000); b.Cap() != 4096 {
+		t.Fatalf("GetSized(3000) cap = %d, want 4096", b.Cap())
+	}
+}
+
+func TestBufferPoolSetBucketSizesDerivedLimits(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:      []int{64, 256},
+		InitialCap:       200,
+		PriorityReserves: []int{1},
+	})
+	if st := p.Stats(); st.SmallLimit != 64 {
+		t.Fatalf("SmallLimit = %d, want 64", st.SmallLimit)
+	}
+	if err := p.SetBucketSizes([]int{1024, 4096}); err != nil {
+		t.Fatal(err)
+	}
+	if st := p.Stats(); st.SmallLimit != 256 {
+		t.Fatalf("SmallLimit after reconfiguration = %d, want 256", st.SmallLimit)
+	}
+	if b := p.GetSized(200); b.Cap() != 256 {
+		t.Fatalf("GetSized(200) cap = %d, want a small-pool buffer of 256", b.Cap())
+	}
+	b := p.GetPriority(0, 1000)
+	if b.Cap() < 1000 || p.Stats().Allocs != 3 {
+		t.Fatalf("expected a resized reserve buffer, cap=%d allocs=%d", b.Cap(), p.Stats().Allocs)
+	}
+
+	fixed := NewBufferPoolWithOptions(PoolOptions{BucketSizes: []int{64, 256}, SmallLimit: 32})
+	if err := fixed.SetBucketSizes([]int{1024}); err != nil {
+		t.Fatal(err)
+	}
+	if st := fixed.Stats(); st.SmallLimit != 32 {
+		t.Fatalf("explicit SmallLimit changed to %d", st.SmallLimit)
+	}
+}
+
+func TestBufferPoolSetBucketSizesConcurrent(t *testing.T) {
+	configs := [][]int{{64, 256, 1024}, {512, 8192}, {128}}
+	for _, opts := range []PoolOptions{
+		{},
+		{Sharded: true},
+		{BoundedFreelists: true},
+		{HotReserve: 4},
+	} {
+		p := NewBufferPoolWithOptions(opts)
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; ; i++ {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					n := (i*37 + w*101) % 10000
+					b := p.GetSized(n)
+					if b.Cap() < n || b.Len() != 0 {
+						t.Errorf("GetSized(%d) cap=%d len=%d", n, b.Cap(), b.Len())
+						return
+					}
+					_, _ = b.Write(make([]byte, n))
+					p.Put(b)
+				}
+			}(w)
+		}
+		for i := 0; i < 200; i++ {
+			if err := p.SetBucketSizes(configs[i%len(configs)]); err != nil {
+				t.Fatal(err)
+			}
+			if i%50 == 0 {
+				p.Drain()
+			}
+		}
+		close(stop)
+		wg.Wait()
+	}
+}
+
+func TestBufferPoolGetZeroed(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{BucketSizes: []int{64, 256}, BoundedFreelists: true})
+	b := p.GetSized(200)
+	_, _ = b.Write(bytes.Repeat([]byte{0xff}, 256))
+	p.Put(b)
+	z := p.GetZeroed(150)
+	if z.Len() != 150 {
+		t.Fatalf("Len = %d, want 150", z.Len())
+	}
+	if !bytes.Equal(z.Bytes(), make([]byte, 150)) {
+		t.Fatalf("GetZeroed returned non-zero bytes")
+	}
+	if got := p.Stats().Gets; got != 2 {
+		t.Fatalf("Gets = %d, want 2", got)
+	}
+}
+
+func TestBufferPoolZeroOnPut(t *testing.T) {
+	secret := []byte("correct horse battery staple")
+	for _, zero := range []bool{false, true} {
+		p := NewBufferPoolWithOptions(PoolOptions{
+			BucketSizes:      []int{64},
+			BoundedFreelists: true,
+			ZeroOnPut:        zero,
+		})
+		b := p.GetSized(len(secret))
+		_, _ = b.Write(secret)
+		p.Put(b)
+		got := p.GetSized(len(secret))
+		if got != b {
+			t.Fatalf("expected the same buffer back")
+		}
+		old := got.UnsafeBytes()[:len(secret)]
+		if wiped := bytes.Equal(old, make([]byte, len(secret))); wiped != zero {
+			t.Fatalf("ZeroOnPut=%v: old contents %q", zero, old)
+		}
+	}
+}
+
+func TestBufferPoolPutClearsMaxSize(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{BucketSizes: []int{64}, BoundedFreelists: true})
+	b := p.GetSized(32)
+	b.SetMaxSize(4)
+	p.Put(b)
+	got := p.GetSized(32)
+	if got != b {
+		t.Fatalf("expected the same buffer back")
+	}
+	if _, err := got.WriteString("longer than four"); err != nil {
+		t.Fatalf("pooled buffer kept the previous SetMaxSize: %v", err)
+	}
+}
+
+func TestBufferPoolPutClearsModes(t *testing.T) {
+	modes := []struct {
+		name  string
+		set   func(*Buffer)
+		reset func(*Buffer) bool
+	}{
+		{"stable", func(b *Buffer) { b.SetStable(true) }, func(b *Buffer) bool { return !b.stable }},
+		{"resumable", func(b *Buffer) { b.SetResumable(true) }, func(b *Buffer) bool { return !b.resumable }},
+		{"fields", func(b *Buffer) { b.TrackFields(true) }, func(b *Buffer) bool { return b.fields == nil }},
+		{"growthHint", func(b *Buffer) { b.SetGrowthHint(4096) }, func(b *Buffer) bool { return b.growthHint == 0 }},
+		{"growth", func(b *Buffer) { b.SetGrowthStrategy(GrowExact) }, func(b *Buffer) bool { return b.growth == GrowPowerOfTwo }},
+	}
+	for _, m := range modes {
+		t.Run(m.name, func(t *testing.T) {
+			p := NewBufferPoolWithOptions(PoolOptions{BucketSizes: []int{64}, BoundedFreelists: true})
+			b := p.GetSized(32)
+			m.set(b)
+			p.Put(b)
+			got := p.GetSized(32)
+			if got != b {
+				t.Fatalf("expected the same buffer back")
+			}
+			if !m.reset(got) {
+				t.Fatalf("pooled buffer kept %s", m.name)
+			}
+		})
+	}
+}
+
+func TestBufferPoolZeroOnPutDrained(t *testing.T) {
+	secret := []byte("SECRET-KEY")
+	p := NewBufferPoolWithOptions(PoolOptions{
+		BucketSizes:      []int{64},
+		BoundedFreelists: true,
+		ZeroOnPut:        true,
+	})
+	b := p.GetSized(len(secret))
+	_, _ = b.Write(secret)
+	_, _ = b.Read(make([]byte, len(secret))) // drained: len rewinds to 0
+	p.Put(b)
+	got := p.GetSized(len(secret))
+	if got != b {
+		t.Fatalf("expected the same buffer back")
+	}
+	if old := got.UnsafeBytes()[:len(secret)]; !bytes.Equal(old, make([]byte, len(secret))) {
+		t.Fatalf("drained contents survived Put: %q", old)
+	}
+}
@@ -0,0 +1,97 @@
+package gobuff
+
+// priorityClass holds a pre-allocated reserve of buffers for one priority class.
+type priorityClass struct {
+	reserve freelist
+	limit   int
+}
+
+func (p *BufferPool) initPriorities(reserves []int, capacity int) {
+	if len(reserves) == 0 {
+		return
+	}
+	if capacity <= 0 {
+		capacity = int(p.defaultCap.Load())
+	} else {
+		p.reserveFixed = true
+	}
+	p.reserveCap.Store(int64(capacity))
+	p.classes = make([]priorityClass, len(reserves))
+	for i, n := range reserves {
+		if n <= 0 {
+			continue
+		}
+		c := &p.classes[i]
+		c.limit = n
+		p.fillReserve(c, capacity)
+	}
+}
+
+func (p *BufferPool) fillReserve(c *priorityClass, capacity int) {
+	for j := 0; j < c.limit; j++ {
+		p.allocs.Add(1)
+		c.reserve.push(p.newBuffer(capacity), c.limit)
+	}
+}
+
+// resizeReserves refills the reserves at the new default capacity after
+// SetBucketSizes moved it, unless PriorityReserveCap fixed their size.
+// Callers hold p.reconfig.
+func (p *BufferPool) resizeReserves() {
+	if len(p.classes) == 0 || p.reserveFixed {
+		return
+	}
+	capacity := p.defaultCap.Load()
+	if p.reserveCap.Swap(capacity) == capacity {
+		return
+	}
+	for i := range p.classes {
+		if c := &p.classes[i]; c.limit > 0 {
+			c.reserve.reset()
+			p.fillReserve(c, int(capacity))
+		}
+	}
+}
+
+// GetPriority retrieves a buffer for n bytes on behalf of a priority class.
+// Requests that fit within PriorityReserveCap are served from the class's
+// reserve first, which other classes cannot drain, and fall back to the shared
+// buckets once the reserve is empty. Unknown classes use the shared buckets.
+// Return the buffer with PutPriority so the reserve is refilled.
+func (p *BufferPool) GetPriority(class int, n int) *Buffer {
+	if class >= 0 && class < len(p.classes) && n <= int(p.reserveCap.Load()) {
+		if buf := p.classes[class].reserve.pop(); buf != nil {
+			p.gets.Add(1)
+			if n > cap(buf.buf) {
+				buf.grow(n) // taken while SetBucketSizes was resizing the reserve
+			}
+			buf.class = int(p.reserveCap.Load())
+			if p.debugLeaks {
+				p.checkout(buf)
+			}
+			return buf
+		}
+	}
+	return p.GetSized(n)
+}
+
+// PutPriority returns b to the reserve of class when it has room, otherwise to
+// the shared buckets as Put does. Either way b is reset exactly as by Put.
+func (p *BufferPool) PutPriority(class int, b *Buffer) {
+	if b == nil {
+		return
+	}
+	if p.debugLeaks && !p.checkin(b) {
+		return
+	}
+	p.puts.Add(1)
+	if !p.recycle(b) {
+		return
+	}
+	if class >= 0 && class < len(p.classes) && cap(b.buf) >= int(p.reserveCap.Load()) {
+		if c := &p.classes[class]; c.limit > 0 && c.reserve.push(b, c.limit) {
+			return
+		}
+	}
+	p.store(b)
+}
@@ -0,0 +1,87 @@
+package gobuff
+
+import (
+	"io"
+	"testing"
+)
+
+func TestBufferPoolPriorityReserve(t *testing.T) {
+	const (
+		high = 0
+		bulk = 1
+	)
+	p := NewBufferPoolWithOptions(PoolOptions{
+		InitialCap:       1024,
+		PriorityReserves: []int{4, 0},
+	})
+
+	// Saturate the bulk class without ever returning buffers.
+	var held []*Buffer
+	for i := 0; i < 64; i++ {
+		held = append(held, p.GetPriority(bulk, 1024))
+	}
+
+	allocs := p.Stats().Allocs
+	var hot []*Buffer
+	for i := 0; i < 4; i++ {
+		b := p.GetPriority(high, 512)
+		if b.Cap() < 512 {
+			t.Fatalf("reserve buffer too small: %d", b.Cap())
+		}
+		hot = append(hot, b)
+	}
+	if got := p.Stats().Allocs; got != allocs {
+		t.Fatalf("high-priority Gets allocated: %d -> %d", allocs, got)
+	}
+
+	for _, b := range hot {
+		p.PutPriority(high, b)
+	}
+	again := p.GetPriority(high, 512)
+	if again != hot[len(hot)-1] {
+		t.Fatalf("expected reserve to be refilled by PutPriority")
+	}
+	_ = held
+}
+
+func TestBufferPoolPutPriorityResetsModes(t *testing.T) {
+	var resets, unread int
+	p := NewBufferPoolWithOptions(PoolOptions{
+		InitialCap:           1024,
+		PriorityReserves:     []int{1},
+		OnReset:              func(*Buffer) { resets++ },
+		DebugWarnUnreadOnPut: true,
+		OnUnreadPut:          func(n int) { unread += n },
+	})
+	b := p.GetPriority(0, 512)
+	_, _ = b.WriteString("0123456789")
+	if _, err := b.Seek(4, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	p.PutPriority(0, b)
+	if resets != 1 || unread != 6 {
+		t.Fatalf("OnReset calls=%d unread=%d, want 1 and 6", resets, unread)
+	}
+	got := p.GetPriority(0, 512)
+	if got != b {
+		t.Fatalf("expected the buffer back from the reserve")
+	}
+	if got.resumable || got.appendOnly || got.Len() != 0 {
+		t.Fatalf("reserve buffer kept per-use state: resumable=%v appendOnly=%v len=%d", got.resumable, got.appendOnly, got.Len())
+	}
+	if st := p.Stats(); st.Puts != 1 {
+		t.Fatalf("Puts = %d, want 1", st.Puts)
+	}
+}
+
+func TestBufferPoolForEachPooledReserves(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		InitialCap:       1024,
+		PriorityReserves: []int{2, 1},
+	})
+	visited := 0
+	p.ForEachPooled(func(*Buffer) { visited++ })
+	if visited != 3 {
+		t.Fatalf("expected to visit the 3 reserve buffers, got %d", visited)
+	}
+}
@@ -0,0 +1,120 @@
+package gobuff
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrReaderClosed is returned by PoolReader reads after Close.
+var ErrReaderClosed = errors.New("gobuff: read from closed PoolReader")
+
+// PoolReader buffers reads from an underlying io.Reader like bufio.Reader,
+// but fills a pooled Buffer and returns it to the pool as soon as the source
+// is exhausted or the reader is closed, so streaming decoders can reuse pool
+// storage without managing buffers themselves.
+//
+// Source errors, including io.EOF, are returned once the buffered data has
+// been consumed and are sticky. A PoolReader is not safe for concurrent use.
+type PoolReader struct {
+	pool   *BufferPool
+	src    io.Reader
+	buf    *Buffer
+	size   int
+	err    error
+	closed bool
+}
+
+// NewReader returns a PoolReader that reads from src in chunks of the pool's
+// default capacity.
+func (p *BufferPool) NewReader(src io.Reader) *PoolReader {
+	return p.NewReaderSize(src, int(p.defaultCap.Load()))
+}
+
+// NewReaderSize is like NewReader but reads chunks of up to size bytes.
+// Reads into slices of at least size bytes with nothing buffered go straight
+// to src.
+func (p *BufferPool) NewReaderSize(src io.Reader, size int) *PoolReader {
+	if size <= 0 {
+		size = int(p.defaultCap.Load())
+	}
+	return &PoolReader{pool: p, src: src, size: size}
+}
+
+// Buffered returns the number of bytes that can be read without reading src.
+func (r *PoolReader) Buffered() int {
+	if r.buf == nil {
+		return 0
+	}
+	return r.buf.Len()
+}
+
+// Read implements io.Reader.
+func (r *PoolReader) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, ErrReaderClosed
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if r.Buffered() == 0 {
+		if r.err != nil {
+			r.release()
+			return 0, r.err
+		}
+		if len(p) >= r.size {
+			n, err := r.src.Read(p)
+			if err != nil {
+				r.err = err
+				r.release()
+			}
+			return n, err
+		}
+		r.fill()
+		if r.Buffered() == 0 {
+			r.release()
+			return 0, r.err
+		}
+	}
+	return r.buf.Read(p)
+}
+
+// fill reads one chunk from src into the empty buffer, taking a buffer from
+// the pool if needed.
+func (r *PoolReader) fill() {
+	if r.buf == nil {
+		r.buf = r.pool.GetSized(r.size)
+	}
+	b := r.buf
+	b.Reset()
+	for i := 0; i < maxConsecutiveEmptyReads; i++ {
+		n, err := r.src.Read(b.buf[:cap(b.buf)])
+		b.buf = b.buf[:n]
+		if err != nil {
+			r.err = err
+			return
+		}
+		if n > 0 {
+			return
+		}
+	}
+	r.err = io.ErrNoProgress
+}
+
+func (r *PoolReader) release() {
+	if r.buf != nil {
+		r.buf.Reset() // unread data is discarded deliberately
+		r.pool.Put(r.buf)
+		r.buf = nil
+	}
+}
+
+// Close returns the buffer to the pool, discarding any unread data. It does
+// not close src. Calls after the first return nil.
+func (r *PoolReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	r.release()
+	return nil
+}
@@ -0,0 +1,58 @@
+package gobuff
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestPoolReaderReadAll(t *testing.T) {
+	pool := NewBufferPoolWithOptions(PoolOptions{})
+	data := strings.Repeat("0123456789", 100)
+	r := pool.NewReaderSize(iotest.HalfReader(strings.NewReader(data)), 64)
+	got, err := io.ReadAll(r)
+	if err != nil || string(got) != data {
+		t.Fatalf("ReadAll = %d bytes, %v", len(got), err)
+	}
+	if r.buf != nil {
+		t.Fatalf("buffer still held after EOF")
+	}
+	if st := pool.Stats(); st.Gets != st.Puts {
+		t.Fatalf("gets=%d puts=%d, want buffer returned at EOF", st.Gets, st.Puts)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Read(make([]byte, 1)); !errors.Is(err, ErrReaderClosed) {
+		t.Fatalf("read after Close: %v", err)
+	}
+}
+
+func TestPoolReaderNoLeaks(t *testing.T) {
+	pool := NewBufferPoolWithOptions(PoolOptions{DebugLeakDetection: true, BoundedFreelists: true})
+	payload := bytes.Repeat([]byte("x"), 300)
+	tmp := make([]byte, 10)
+	for i := 0; i < 200; i++ {
+		r := pool.NewReaderSize(bytes.NewReader(payload), 128)
+		if i%2 == 0 {
+			_, _ = r.Read(tmp) // stop early
+		} else {
+			_, _ = io.Copy(io.Discard, r)
+		}
+		_ = r.Close()
+		_ = r.Close()
+	}
+	for i := 0; i < 3; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	st := pool.Stats()
+	if st.LeakCount != 0 || st.DoublePuts != 0 || st.Gets != st.Puts {
+		t.Fatalf("LeakCount=%d DoublePuts=%d gets=%d puts=%d", st.LeakCount, st.DoublePuts, st.Gets, st.Puts)
+	}
+}
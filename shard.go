@@ -0,0 +1,77 @@
+package gobuff
+
+import (
+	"sync"
+	_ "unsafe" // for go:linkname
+)
+
+// smallSlot identifies the small-buffer pool where a bucket index is expected.
+const smallSlot = -1
+
+// shardedSet splits every bucket into independent sync.Pools. The shard pools
+// have no New funcs so a miss can fall through to a neighbour before the
+// caller allocates.
+type shardedSet struct {
+	layout *bucketLayout
+	shards []shard
+}
+
+type shard struct {
+	buckets []sync.Pool
+	small   sync.Pool
+	_       [cacheLineSize]byte
+}
+
+func (p *BufferPool) newShardedSet(l *bucketLayout, n int) *shardedSet {
+	if n < 1 {
+		n = 1
+	}
+	s := &shardedSet{layout: l, shards: make([]shard, n)}
+	for i := range s.shards {
+		s.shards[i].buckets = make([]sync.Pool, len(l.sizes))
+	}
+	return s
+}
+
+func (s *shardedSet) pool(i, slot int) *sync.Pool {
+	sh := &s.shards[i]
+	if slot == smallSlot {
+		return &sh.small
+	}
+	return &sh.buckets[slot]
+}
+
+// hint returns the calling P's shard, so a Put and a later Get on the same P
+// meet in one shard. Pinning only reads the P's id; it is released at once
+// since a stale id merely costs locality.
+func (s *shardedSet) hint() int {
+	pid := runtime_procPin()
+	runtime_procUnpin()
+	return pid % len(s.shards)
+}
+
+// procPin and procUnpin are the hooks sync.Pool uses to find its per-P slot.
+//
+//go:linkname runtime_procPin runtime.procPin
+func runtime_procPin() int
+
+//go:linkname runtime_procUnpin runtime.procUnpin
+func runtime_procUnpin()
+
+// get tries the local shard and then its neighbour, returning nil on a miss.
+func (s *shardedSet) get(slot int) *Buffer {
+	i := s.hint()
+	if b, _ := s.pool(i, slot).Get().(*Buffer); b != nil {
+		return b
+	}
+	if len(s.shards) > 1 {
+		if b, _ := s.pool((i+1)%len(s.shards), slot).Get().(*Buffer); b != nil {
+			return b
+		}
+	}
+	return nil
+}
+
+func (s *shardedSet) put(slot int, b *Buffer) {
+	s.pool(s.hint(), slot).Put(b)
+}
@@ -0,0 +1,116 @@
+package gobuff
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultThroughputWindows = 60
+
+// IntervalStat holds the gets and puts observed during one throughput interval.
+type IntervalStat struct {
+	Start time.Time
+	End   time.Time
+	Gets  int64
+	Puts  int64
+}
+
+// throughputRing keeps the most recent interval counts, fed by a ticker.
+type throughputRing struct {
+	p    *BufferPool
+	mu   sync.Mutex
+	ring []IntervalStat
+	next int
+	full bool
+
+	start     time.Time
+	lastGets  int64
+	lastPuts  int64
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newThroughputRing(p *BufferPool, interval time.Duration, windows int) *throughputRing {
+	if windows <= 0 {
+		windows = defaultThroughputWindows
+	}
+	t := &throughputRing{
+		p:     p,
+		ring:  make([]IntervalStat, windows),
+		start: time.Now(),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go t.run(interval)
+	return t
+}
+
+func (t *throughputRing) run(interval time.Duration) {
+	defer close(t.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			t.roll(now)
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+// roll closes the current interval at now and starts the next one.
+func (t *throughputRing) roll(now time.Time) {
+	gets, puts := t.p.gets.Load(), t.p.puts.Load()
+	t.mu.Lock()
+	t.ring[t.next] = IntervalStat{
+		Start: t.start,
+		End:   now,
+		Gets:  gets - t.lastGets,
+		Puts:  puts - t.lastPuts,
+	}
+	t.next++
+	if t.next == len(t.ring) {
+		t.next = 0
+		t.full = true
+	}
+	t.start, t.lastGets, t.lastPuts = now, gets, puts
+	t.mu.Unlock()
+}
+
+func (t *throughputRing) snapshot() []IntervalStat {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.full {
+		return append([]IntervalStat(nil), t.ring[:t.next]...)
+	}
+	out := make([]IntervalStat, 0, len(t.ring))
+	out = append(out, t.ring[t.next:]...)
+	return append(out, t.ring[:t.next]...)
+}
+
+func (t *throughputRing) close() {
+	t.closeOnce.Do(func() {
+		close(t.stop)
+		<-t.done
+	})
+}
+
+// RecentThroughput returns the recorded intervals, oldest first. It returns nil
+// unless the pool was created with a positive ThroughputInterval.
+func (p *BufferPool) RecentThroughput() []IntervalStat {
+	if p.throughput == nil {
+		return nil
+	}
+	return p.throughput.snapshot()
+}
+
+// Close stops background work started by the pool, such as the throughput
+// ticker. The pool remains usable; Close is safe to call more than once.
+func (p *BufferPool) Close() error {
+	if p.throughput != nil {
+		p.throughput.close()
+	}
+	return nil
+}
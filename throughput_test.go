@@ -0,0 +1,42 @@
+package gobuff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBufferPoolRecentThroughput(t *testing.T) {
+	p := NewBufferPoolWithOptions(PoolOptions{
+		ThroughputInterval: time.Hour, // intervals are advanced manually below
+		ThroughputWindows:  2,
+	})
+	defer p.Close()
+
+	base := time.Unix(1000, 0)
+	p.throughput.start = base
+	for i := 0; i < 3; i++ {
+		p.Put(p.Get())
+	}
+	p.throughput.roll(base.Add(time.Second))
+	for i := 0; i < 5; i++ {
+		_ = p.Get()
+	}
+	p.throughput.roll(base.Add(2 * time.Second))
+	p.Put(p.Get())
+	p.throughput.roll(base.Add(3 * time.Second))
+
+	got := p.RecentThroughput()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 retained intervals, got %d", len(got))
+	}
+	if got[0].Gets != 5 || got[0].Puts != 0 || !got[0].End.Equal(base.Add(2*time.Second)) {
+		t.Fatalf("unexpected oldest interval: %+v", got[0])
+	}
+	if got[1].Gets != 1 || got[1].Puts != 1 || !got[1].Start.Equal(base.Add(2*time.Second)) {
+		t.Fatalf("unexpected newest interval: %+v", got[1])
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+}
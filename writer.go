@@ -0,0 +1,140 @@
+package gobuff
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrWriterClosed is returned by PoolWriter writes after Close.
+var ErrWriterClosed = errors.New("gobuff: write to closed PoolWriter")
+
+// PoolWriter buffers writes to an underlying io.Writer like bufio.Writer, but
+// holds a pooled Buffer only while data is pending: the buffer is taken on the
+// first write and returned to the pool after each successful Flush, so many
+// mostly idle writers do not each pin a buffer.
+//
+// Once a write to the destination fails, including a short write reported as
+// *ShortWriteError, the error is sticky and the unwritten bytes stay buffered
+// until Close. A PoolWriter is not safe for concurrent use.
+type PoolWriter struct {
+	pool      *BufferPool
+	dst       io.Writer
+	buf       *Buffer
+	threshold int
+	err       error
+	closed    bool
+}
+
+// NewWriter returns a PoolWriter that flushes to dst once the pool's default
+// capacity is buffered.
+func (p *BufferPool) NewWriter(dst io.Writer) *PoolWriter {
+	return p.NewWriterSize(dst, int(p.defaultCap.Load()))
+}
+
+// NewWriterSize is like NewWriter but flushes once size bytes are buffered.
+// Writes of at least size bytes with nothing pending go straight to dst.
+func (p *BufferPool) NewWriterSize(dst io.Writer, size int) *PoolWriter {
+	if size <= 0 {
+		size = int(p.defaultCap.Load())
+	}
+	return &PoolWriter{pool: p, dst: dst, threshold: size}
+}
+
+// Buffered returns the number of bytes waiting to be flushed.
+func (w *PoolWriter) Buffered() int {
+	if w.buf == nil {
+		return 0
+	}
+	return w.buf.Len()
+}
+
+// Write buffers p, flushing to the destination when the threshold is reached.
+func (w *PoolWriter) Write(p []byte) (int, error) {
+	if err := w.check(); err != nil {
+		return 0, err
+	}
+	if w.Buffered() == 0 && len(p) >= w.threshold {
+		n, err := w.dst.Write(p)
+		return n, w.direct(n, len(p), err)
+	}
+	n, _ := w.buffer().Write(p)
+	return n, w.flushIfFull()
+}
+
+// WriteString is like Write but takes a string.
+func (w *PoolWriter) WriteString(s string) (int, error) {
+	if err := w.check(); err != nil {
+		return 0, err
+	}
+	if w.Buffered() == 0 && len(s) >= w.threshold {
+		n, err := io.WriteString(w.dst, s)
+		return n, w.direct(n, len(s), err)
+	}
+	n, _ := w.buffer().WriteString(s)
+	return n, w.flushIfFull()
+}
+
+func (w *PoolWriter) check() error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	return w.err
+}
+
+// direct records the outcome of a write that bypassed the buffer.
+func (w *PoolWriter) direct(n, total int, err error) error {
+	if err == nil && n != total {
+		err = &ShortWriteError{Written: n, Total: total}
+	}
+	w.err = err
+	return err
+}
+
+// buffer returns the pending buffer, taking one from the pool if needed.
+func (w *PoolWriter) buffer() *Buffer {
+	if w.buf == nil {
+		w.buf = w.pool.GetSized(w.threshold)
+	}
+	return w.buf
+}
+
+func (w *PoolWriter) flushIfFull() error {
+	if w.buf.Len() < w.threshold {
+		return nil
+	}
+	return w.Flush()
+}
+
+// Flush writes any buffered data to the destination and returns the buffer
+// to the pool.
+func (w *PoolWriter) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.buf == nil {
+		return nil
+	}
+	if _, err := w.buf.WriteTo(w.dst); err != nil {
+		w.err = err
+		return err
+	}
+	w.pool.Put(w.buf)
+	w.buf = nil
+	return nil
+}
+
+// Close flushes buffered data and returns the buffer to the pool even if the
+// flush fails, discarding what could not be written. It does not close the
+// destination. Calls after the first return nil.
+func (w *PoolWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	err := w.Flush()
+	if w.buf != nil {
+		w.pool.Put(w.buf)
+		w.buf = nil
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package gobuff
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestPoolWriterFlushAndClose(t *testing.T) {
+	pool := NewBufferPoolWithOptions(PoolOptions{BucketSizes: []int{64}, SmallLimit: 16})
+	var dst bytes.Buffer
+	w := pool.NewWriterSize(&dst, 8)
+	_, _ = w.WriteString("abc")
+	if dst.Len() != 0 || w.Buffered() != 3 {
+		t.Fatalf("dst=%q buffered=%d, want data held", dst.String(), w.Buffered())
+	}
+	_, _ = w.Write([]byte("defgh")) // reaches the threshold
+	if dst.String() != "abcdefgh" || w.Buffered() != 0 {
+		t.Fatalf("dst=%q buffered=%d after threshold", dst.String(), w.Buffered())
+	}
+	if st := pool.Stats(); st.Gets != st.Puts {
+		t.Fatalf("gets=%d puts=%d, want buffer returned after flush", st.Gets, st.Puts)
+	}
+	_, _ = w.WriteString("ij")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+	if dst.String() != "abcdefghij" {
+		t.Fatalf("dst=%q", dst.String())
+	}
+	if st := pool.Stats(); st.Gets != 2 || st.Puts != 2 {
+		t.Fatalf("gets=%d puts=%d, want 2 and 2", st.Gets, st.Puts)
+	}
+	if _, err := w.Write([]byte("x")); !errors.Is(err, ErrWriterClosed) {
+		t.Fatalf("write after Close: %v", err)
+	}
+}
+
+func TestPoolWriterShortWrite(t *testing.T) {
+	pool := NewBufferPoolWithOptions(PoolOptions{})
+	w := pool.NewWriterSize(shortWriter{w: io.Discard, limit: 2}, 4)
+	if _, err := w.WriteString("abcd"); !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("direct write err = %v, want short write", err)
+	}
+	if _, err := w.WriteString("x"); !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("error not sticky: %v", err)
+	}
+	if err := w.Close(); !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("Close err = %v", err)
+	}
+
+	w = pool.NewWriterSize(shortWriter{w: io.Discard, limit: 2}, 4)
+	_, _ = w.WriteString("ab")
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.WriteString("abc")
+	if err := w.Flush(); !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("Flush err = %v, want short write", err)
+	}
+	if w.Buffered() != 1 {
+		t.Fatalf("buffered = %d, want unwritten byte kept", w.Buffered())
+	}
+	_ = w.Close()
+	if st := pool.Stats(); st.Gets != st.Puts {
+		t.Fatalf("gets=%d puts=%d, want buffer returned by Close", st.Gets, st.Puts)
+	}
+}